@@ -0,0 +1,71 @@
+// Package cache maps keys to values in memory, keeping the values recently used up to a capacity. It is
+// used to keep the hot blocks of tables in memory.
+package cache
+
+// Cache maps keys to values, which have a charge against the capacity of the cache. When the charges
+// add up to more than the capacity, the least recently used entries are evicted.
+//
+// An entry stays in memory while there are handles to it, even once it is evicted or erased, and an
+// entry with handles is never evicted. Every handle returned by Insert or Lookup must be released once
+// it is no longer used. A Cache is safe to use from multiple goroutines.
+type Cache interface {
+	// Insert maps key to value, replacing any entry for key, and returns a handle to the new entry.
+	// deleter, when not nil, is called with the key and value once the entry is out of the cache and
+	// has no handles left.
+	Insert(key []byte, value interface{}, charge int64, deleter func(key []byte, value interface{})) *Handle
+
+	// Lookup returns a handle to the entry for key, or nil when there is none.
+	Lookup(key []byte) *Handle
+
+	// Release gives back a handle returned by Insert or Lookup. The handle cannot be used afterwards.
+	Release(h *Handle)
+
+	// Erase removes the entry for key. It stays in memory until the handles to it are released.
+	Erase(key []byte)
+
+	// NewID returns a new number, to prefix the keys of clients sharing the cache with.
+	NewID() uint64
+
+	// Prune removes the entries that have no handles.
+	Prune()
+
+	// TotalCharge returns the combined charge of the entries in the cache.
+	TotalCharge() int64
+
+	// Stats returns the counts of lookups and evictions so far.
+	Stats() Stats
+}
+
+// Stats count what happened to the entries of a cache.
+type Stats struct {
+	// Hits and Misses count the lookups that found an entry and those that did not.
+	Hits   int64
+	Misses int64
+	// Evictions counts the entries removed to make room for others.
+	Evictions int64
+}
+
+func (s *Stats) add(o Stats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Evictions += o.Evictions
+}
+
+// Handle is a reference to an entry of a Cache, which keeps the entry in memory.
+type Handle struct {
+	key     []byte
+	value   interface{}
+	charge  int64
+	deleter func(key []byte, value interface{})
+
+	// refs counts the handles to the entry, and the cache itself while the entry is in it.
+	refs    int
+	inCache bool
+	// prev and next link the entry into the lru or in use list of its shard.
+	prev, next *Handle
+}
+
+// Value returns the value of the entry.
+func (h *Handle) Value() interface{} {
+	return h.value
+}
@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+const numShardBits = 4
+
+// NewLRUCache creates a cache of capacity, which is split among shards that each have their own lock,
+// so that goroutines using different keys rarely wait for each other. Each shard rounds its part of the
+// capacity up, so the cache can hold a little more than capacity. A capacity of zero turns caching off:
+// entries are only kept while there are handles to them.
+func NewLRUCache(capacity int64) Cache {
+	c := &shardedCache{}
+	perShard := (capacity + (1 << numShardBits) - 1) >> numShardBits
+	for i := range c.shards {
+		c.shards[i].init(perShard)
+	}
+	return c
+}
+
+type shardedCache struct {
+	shards [1 << numShardBits]lruShard
+	lastID uint64
+}
+
+func (c *shardedCache) shard(key []byte) *lruShard {
+	h := fnv.New32a()
+	h.Write(key)
+	return &c.shards[h.Sum32()>>(32-numShardBits)]
+}
+
+func (c *shardedCache) Insert(key []byte, value interface{}, charge int64, deleter func(key []byte, value interface{})) *Handle {
+	return c.shard(key).insert(key, value, charge, deleter)
+}
+
+func (c *shardedCache) Lookup(key []byte) *Handle {
+	return c.shard(key).lookup(key)
+}
+
+func (c *shardedCache) Release(h *Handle) {
+	c.shard(h.key).release(h)
+}
+
+func (c *shardedCache) Erase(key []byte) {
+	c.shard(key).erase(key)
+}
+
+func (c *shardedCache) NewID() uint64 {
+	return atomic.AddUint64(&c.lastID, 1)
+}
+
+func (c *shardedCache) Prune() {
+	for i := range c.shards {
+		c.shards[i].prune()
+	}
+}
+
+func (c *shardedCache) TotalCharge() int64 {
+	var total int64
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		total += s.usage
+		s.mu.Unlock()
+	}
+	return total
+}
+
+func (c *shardedCache) Stats() Stats {
+	var stats Stats
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		stats.add(s.stats)
+		s.mu.Unlock()
+	}
+	return stats
+}
+
+// lruShard is a part of a shardedCache.
+//
+// Its entries are in one of two circular lists: the in use list holds the entries with handles, in no
+// particular order, and the lru list the others, from the least to the most recently used. Only the
+// entries of the lru list can be evicted. Entries that were erased or evicted while they had handles
+// are in neither list.
+type lruShard struct {
+	mu       sync.Mutex
+	capacity int64
+	usage    int64
+	lru      Handle
+	inUse    Handle
+	table    map[string]*Handle
+	stats    Stats
+}
+
+func (s *lruShard) init(capacity int64) {
+	s.capacity = capacity
+	s.lru.prev, s.lru.next = &s.lru, &s.lru
+	s.inUse.prev, s.inUse.next = &s.inUse, &s.inUse
+	s.table = map[string]*Handle{}
+}
+
+func (s *lruShard) insert(key []byte, value interface{}, charge int64, deleter func(key []byte, value interface{})) *Handle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := &Handle{key: append([]byte(nil), key...), value: value, charge: charge, deleter: deleter, refs: 1}
+	if s.capacity > 0 {
+		h.refs++
+		h.inCache = true
+		appendTo(&s.inUse, h)
+		s.usage += charge
+		if old := s.table[string(key)]; old != nil {
+			s.finishErase(old)
+		}
+		s.table[string(key)] = h
+	}
+
+	for s.usage > s.capacity && s.lru.next != &s.lru {
+		oldest := s.lru.next
+		delete(s.table, string(oldest.key))
+		s.finishErase(oldest)
+		s.stats.Evictions++
+	}
+	return h
+}
+
+func (s *lruShard) lookup(key []byte) *Handle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.table[string(key)]
+	if h == nil {
+		s.stats.Misses++
+		return nil
+	}
+	s.stats.Hits++
+	s.ref(h)
+	return h
+}
+
+func (s *lruShard) release(h *Handle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unref(h)
+}
+
+func (s *lruShard) erase(key []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if h := s.table[string(key)]; h != nil {
+		delete(s.table, string(key))
+		s.finishErase(h)
+	}
+}
+
+func (s *lruShard) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.lru.next != &s.lru {
+		h := s.lru.next
+		delete(s.table, string(h.key))
+		s.finishErase(h)
+	}
+}
+
+func (s *lruShard) ref(h *Handle) {
+	if h.refs == 1 && h.inCache {
+		// The entry gets its first handle, so it can no longer be evicted.
+		remove(h)
+		appendTo(&s.inUse, h)
+	}
+	h.refs++
+}
+
+func (s *lruShard) unref(h *Handle) {
+	h.refs--
+	switch {
+	case h.refs == 0:
+		if h.deleter != nil {
+			h.deleter(h.key, h.value)
+		}
+	case h.inCache && h.refs == 1:
+		// The last handle is gone, so the entry can be evicted again.
+		remove(h)
+		appendTo(&s.lru, h)
+	}
+}
+
+// finishErase takes h, which was just removed from the table, out of the cache.
+func (s *lruShard) finishErase(h *Handle) {
+	remove(h)
+	h.inCache = false
+	s.usage -= h.charge
+	s.unref(h)
+}
+
+// appendTo makes h the newest entry of list.
+func appendTo(list, h *Handle) {
+	h.next = list
+	h.prev = list.prev
+	h.prev.next = h
+	h.next.prev = h
+}
+
+func remove(h *Handle) {
+	h.next.prev = h.prev
+	h.prev.next = h.next
+	h.prev, h.next = nil, nil
+}
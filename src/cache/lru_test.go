@@ -0,0 +1,300 @@
+package cache
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+)
+
+// maxCharge returns the charge a cache of capacity holds once it evicted what it could, as each shard
+// rounds its part of the capacity up.
+func maxCharge(capacity int64) int64 {
+	const numShards = 1 << numShardBits
+	return (capacity + numShards - 1) / numShards * numShards
+}
+
+func encodeKey(k int) []byte {
+	var key [4]byte
+	binary.LittleEndian.PutUint32(key[:], uint32(k))
+	return key[:]
+}
+
+func decodeKey(key []byte) int {
+	return int(binary.LittleEndian.Uint32(key))
+}
+
+// testCache wraps a cache of int keys and values, recording the entries deleted.
+type testCache struct {
+	Cache
+	deletedKeys   []int
+	deletedValues []int
+}
+
+func newTestCache(capacity int64) *testCache {
+	return &testCache{Cache: NewLRUCache(capacity)}
+}
+
+func (c *testCache) deleter(key []byte, value interface{}) {
+	c.deletedKeys = append(c.deletedKeys, decodeKey(key))
+	c.deletedValues = append(c.deletedValues, value.(int))
+}
+
+// lookup returns the value of key, or -1 when the cache has none.
+func (c *testCache) lookup(key int) int {
+	h := c.Lookup(encodeKey(key))
+	if h == nil {
+		return -1
+	}
+	defer c.Release(h)
+	return h.Value().(int)
+}
+
+func (c *testCache) insert(key, value int, charge int64) {
+	c.Release(c.Cache.Insert(encodeKey(key), value, charge, c.deleter))
+}
+
+func (c *testCache) insertAndReturnHandle(key, value int) *Handle {
+	return c.Cache.Insert(encodeKey(key), value, 1, c.deleter)
+}
+
+func (c *testCache) erase(key int) {
+	c.Erase(encodeKey(key))
+}
+
+func TestLRUCache_HitAndMiss(t *testing.T) {
+	c := newTestCache(1000)
+	expectLookup := func(key, expected int) {
+		if actual := c.lookup(key); actual != expected {
+			t.Errorf("Expected %v for key %v but got %v", expected, key, actual)
+		}
+	}
+
+	expectLookup(100, -1)
+	c.insert(100, 101, 1)
+	expectLookup(100, 101)
+	expectLookup(200, -1)
+	expectLookup(300, -1)
+
+	c.insert(200, 201, 1)
+	expectLookup(100, 101)
+	expectLookup(200, 201)
+	expectLookup(300, -1)
+
+	c.insert(100, 102, 1)
+	expectLookup(100, 102)
+	expectLookup(200, 201)
+	expectLookup(300, -1)
+
+	if len(c.deletedKeys) != 1 || c.deletedKeys[0] != 100 || c.deletedValues[0] != 101 {
+		t.Errorf("Expected the replaced entry 100 -> 101 to be deleted but got %v -> %v", c.deletedKeys, c.deletedValues)
+	}
+	if stats := c.Stats(); stats.Hits != 5 || stats.Misses != 5 || stats.Evictions != 0 {
+		t.Errorf("Expected 5 hits and 5 misses but got %+v", stats)
+	}
+}
+
+func TestLRUCache_Erase(t *testing.T) {
+	c := newTestCache(1000)
+	c.erase(200)
+	if len(c.deletedKeys) != 0 {
+		t.Errorf("Expected nothing deleted but got %v", c.deletedKeys)
+	}
+
+	c.insert(100, 101, 1)
+	c.insert(200, 201, 1)
+	c.erase(100)
+	if c.lookup(100) != -1 || c.lookup(200) != 201 {
+		t.Errorf("Expected only 200 to remain but got %v and %v", c.lookup(100), c.lookup(200))
+	}
+	if len(c.deletedKeys) != 1 || c.deletedKeys[0] != 100 || c.deletedValues[0] != 101 {
+		t.Errorf("Expected 100 -> 101 to be deleted but got %v -> %v", c.deletedKeys, c.deletedValues)
+	}
+
+	c.erase(100)
+	if len(c.deletedKeys) != 1 {
+		t.Errorf("Expected erasing twice to delete once but got %v", c.deletedKeys)
+	}
+}
+
+func TestLRUCache_EntriesArePinned(t *testing.T) {
+	c := newTestCache(1000)
+	c.insert(100, 101, 1)
+	h1 := c.Lookup(encodeKey(100))
+	c.insert(100, 102, 1)
+	h2 := c.Lookup(encodeKey(100))
+
+	if h1.Value().(int) != 101 || h2.Value().(int) != 102 {
+		t.Errorf("Expected the handles to see 101 and 102 but got %v and %v", h1.Value(), h2.Value())
+	}
+	if len(c.deletedKeys) != 0 {
+		t.Errorf("Expected the replaced entry to stay while it has a handle but got %v deleted", c.deletedKeys)
+	}
+
+	c.Release(h1)
+	if len(c.deletedKeys) != 1 || c.deletedValues[0] != 101 {
+		t.Errorf("Expected 101 to be deleted with its last handle but got %v", c.deletedValues)
+	}
+
+	c.erase(100)
+	if c.lookup(100) != -1 || len(c.deletedKeys) != 1 {
+		t.Errorf("Expected the erased entry to be gone but kept while it has a handle but got %v deleted", c.deletedValues)
+	}
+
+	c.Release(h2)
+	if len(c.deletedKeys) != 2 || c.deletedValues[1] != 102 {
+		t.Errorf("Expected 102 to be deleted with its last handle but got %v", c.deletedValues)
+	}
+}
+
+func TestLRUCache_EvictionPolicy(t *testing.T) {
+	c := newTestCache(1000)
+	c.insert(100, 101, 1)
+	c.insert(200, 201, 1)
+	c.insert(300, 301, 1)
+	h := c.Lookup(encodeKey(300))
+
+	// Frequently used entries must be kept around, as must entries with handles.
+	for i := 0; i < 2000; i++ {
+		c.insert(1000+i, 2000+i, 1)
+		if actual := c.lookup(1000 + i); actual != 2000+i {
+			t.Fatalf("Expected %v but got %v", 2000+i, actual)
+		}
+		if actual := c.lookup(100); actual != 101 {
+			t.Fatalf("Expected the frequently used 100 -> 101 but got %v", actual)
+		}
+	}
+
+	if actual := c.lookup(100); actual != 101 {
+		t.Errorf("Expected the frequently used 100 -> 101 but got %v", actual)
+	}
+	if actual := c.lookup(200); actual != -1 {
+		t.Errorf("Expected 200 to be evicted but got %v", actual)
+	}
+	if actual := c.lookup(300); actual != 301 {
+		t.Errorf("Expected the pinned 300 -> 301 but got %v", actual)
+	}
+	if c.Stats().Evictions == 0 || c.TotalCharge() > maxCharge(1000) {
+		t.Errorf("Expected evictions to keep the charge within %v but got %+v and %v", maxCharge(1000), c.Stats(), c.TotalCharge())
+	}
+	c.Release(h)
+}
+
+func TestLRUCache_UseExceedingCapacity(t *testing.T) {
+	c := newTestCache(1000)
+
+	// Pinned entries cannot be evicted, so the cache goes over capacity.
+	var handles []*Handle
+	for i := 0; i < 1100; i++ {
+		handles = append(handles, c.insertAndReturnHandle(1000+i, 2000+i))
+	}
+	for i := range handles {
+		if actual := c.lookup(1000 + i); actual != 2000+i {
+			t.Fatalf("Expected %v but got %v", 2000+i, actual)
+		}
+	}
+	if c.TotalCharge() != 1100 {
+		t.Errorf("Expected a charge of 1100 but got %v", c.TotalCharge())
+	}
+
+	for _, h := range handles {
+		c.Release(h)
+	}
+	c.insert(0, 0, 1)
+	c.Prune()
+	if c.TotalCharge() != 0 || len(c.deletedKeys) != 1101 {
+		t.Errorf("Expected everything to be deleted once released and pruned but got %v deleted and a charge of %v",
+			len(c.deletedKeys), c.TotalCharge())
+	}
+}
+
+func TestLRUCache_HeavyEntries(t *testing.T) {
+	// Entries of charge 1 and 10, adding up to twice the capacity.
+	const light, heavy = 1, 10
+	c := newTestCache(1000)
+	added := 0
+	for index := 0; added < 2*1000; index++ {
+		weight := int64(light)
+		if index&1 == 1 {
+			weight = heavy
+		}
+		c.insert(index, 1000+index, weight)
+		added += int(weight)
+	}
+
+	cached := int64(0)
+	for i := 0; i < added; i++ {
+		if r := c.lookup(i); r >= 0 {
+			if r != 1000+i {
+				t.Errorf("Expected %v but got %v", 1000+i, r)
+			}
+			if i&1 == 1 {
+				cached += heavy
+			} else {
+				cached += light
+			}
+		}
+	}
+	if cached > 1000+1000/10 {
+		t.Errorf("Expected about 1000 cached but got %v", cached)
+	}
+}
+
+func TestLRUCache_NewID(t *testing.T) {
+	c := NewLRUCache(1000)
+	if a, b := c.NewID(), c.NewID(); a == b {
+		t.Errorf("Expected different IDs but got %v twice", a)
+	}
+}
+
+func TestLRUCache_Prune(t *testing.T) {
+	c := newTestCache(1000)
+	c.insert(1, 100, 1)
+	c.insert(2, 200, 1)
+	h := c.Lookup(encodeKey(1))
+
+	c.Prune()
+	c.Release(h)
+
+	if c.lookup(1) != 100 || c.lookup(2) != -1 {
+		t.Errorf("Expected only the pinned entry 1 to survive but got %v and %v", c.lookup(1), c.lookup(2))
+	}
+}
+
+func TestLRUCache_ZeroCapacity(t *testing.T) {
+	c := newTestCache(0)
+	c.insert(1, 100, 1)
+	if actual := c.lookup(1); actual != -1 {
+		t.Errorf("Expected nothing cached but got %v", actual)
+	}
+	if len(c.deletedKeys) != 1 {
+		t.Errorf("Expected the entry to be deleted once released but got %v", c.deletedKeys)
+	}
+}
+
+func TestLRUCache_Concurrent(t *testing.T) {
+	c := NewLRUCache(100)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := encodeKey((g*1000 + i) % 300)
+				if h := c.Lookup(key); h != nil {
+					c.Release(h)
+				} else {
+					c.Release(c.Insert(key, i, 1, nil))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	stats := c.Stats()
+	if stats.Hits+stats.Misses != 8*1000 {
+		t.Errorf("Expected %v lookups but got %+v", 8*1000, stats)
+	}
+	if c.TotalCharge() > maxCharge(100) {
+		t.Errorf("Expected a charge of at most %v but got %v", maxCharge(100), c.TotalCharge())
+	}
+}
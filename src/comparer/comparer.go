@@ -0,0 +1,62 @@
+// Package comparer defines the order of user keys in the memtable and in sorted tables.
+package comparer
+
+import "bytes"
+
+// Comparator is a total order on keys. A database must always be opened with the comparator it was
+// created with, which is checked by Name.
+type Comparator interface {
+	// Compare returns -1, 0 or +1 when a is less than, equal to or greater than b.
+	Compare(a, b []byte) int
+
+	// Name identifies the order. It should change whenever the order does.
+	Name() string
+
+	// FindShortestSeparator returns a key that is at least start and less than limit, when start is
+	// less than limit. It is used to keep index blocks small, so it should return a short key, and
+	// can always return start. The result must not share memory with start or limit.
+	FindShortestSeparator(start, limit []byte) []byte
+
+	// FindShortSuccessor returns a short key that is at least key. It can always return key. The
+	// result must not share memory with key.
+	FindShortSuccessor(key []byte) []byte
+}
+
+// BytewiseComparator orders keys by their bytes, as bytes.Compare does. It is LevelDB's default.
+var BytewiseComparator Comparator = bytewiseComparator{}
+
+type bytewiseComparator struct{}
+
+func (bytewiseComparator) Compare(a, b []byte) int {
+	return bytes.Compare(a, b)
+}
+
+func (bytewiseComparator) Name() string {
+	return "leveldb.BytewiseComparator"
+}
+
+func (bytewiseComparator) FindShortestSeparator(start, limit []byte) []byte {
+	shared := 0
+	for shared < len(start) && shared < len(limit) && start[shared] == limit[shared] {
+		shared++
+	}
+	if shared < len(start) && shared < len(limit) {
+		if c := start[shared]; c < 0xff && c+1 < limit[shared] {
+			separator := append([]byte(nil), start[:shared+1]...)
+			separator[shared]++
+			return separator
+		}
+	}
+	return append([]byte(nil), start...)
+}
+
+func (bytewiseComparator) FindShortSuccessor(key []byte) []byte {
+	for i, c := range key {
+		if c != 0xff {
+			successor := append([]byte(nil), key[:i+1]...)
+			successor[i]++
+			return successor
+		}
+	}
+	return append([]byte(nil), key...)
+}
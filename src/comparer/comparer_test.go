@@ -0,0 +1,71 @@
+package comparer
+
+import "testing"
+
+func TestBytewiseComparator_Compare(t *testing.T) {
+	tests := map[string]struct {
+		a, b     string
+		expected int
+	}{
+		"Equal":          {"abc", "abc", 0},
+		"Less":           {"abc", "abd", -1},
+		"Greater":        {"abd", "abc", 1},
+		"Prefix is less": {"ab", "abc", -1},
+		"Empty is least": {"", "a", -1},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if actual := BytewiseComparator.Compare([]byte(test.a), []byte(test.b)); actual != test.expected {
+				t.Errorf("Expected %v but got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBytewiseComparator_FindShortestSeparator(t *testing.T) {
+	tests := map[string]struct {
+		start, limit string
+		expected     string
+	}{
+		"Shortened":               {"abcdefg", "abzz", "abd"},
+		"Adjacent bytes":          {"abc1", "abc2", "abc1"},
+		"Start is a prefix":       {"abc", "abcdef", "abc"},
+		"Limit is a prefix":       {"abcdef", "abc", "abcdef"},
+		"Equal":                   {"abc", "abc", "abc"},
+		"0xff cannot be bumped":   {"a\xff\x01", "b", "a\xff\x01"},
+		"Empty start":             {"", "b", ""},
+		"First byte differs by 2": {"a", "c", "b"},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := BytewiseComparator.FindShortestSeparator([]byte(test.start), []byte(test.limit))
+			if string(actual) != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBytewiseComparator_FindShortSuccessor(t *testing.T) {
+	tests := map[string]struct {
+		key      string
+		expected string
+	}{
+		"Shortened":    {"abc", "b"},
+		"Leading 0xff": {"\xff\xffabc", "\xff\xffb"},
+		"All 0xff":     {"\xff\xff", "\xff\xff"},
+		"Empty":        {"", ""},
+		"Single byte":  {"a", "b"},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := BytewiseComparator.FindShortSuccessor([]byte(test.key))
+			if string(actual) != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Package crc32c computes the CRC-32C (Castagnoli) checksums that LevelDB stores, masked, in log
+// records and table blocks.
+package crc32c
+
+import (
+	"hash"
+	"hash/crc32"
+)
+
+// Table is the Castagnoli table.
+var Table = crc32.MakeTable(crc32.Castagnoli)
+
+// New creates a hash.Hash32 computing the CRC-32C checksum.
+func New() hash.Hash32 {
+	return crc32.New(Table)
+}
+
+// Checksum returns the CRC-32C checksum of p.
+func Checksum(p []byte) uint32 {
+	return crc32.Checksum(p, Table)
+}
+
+// maskDelta is added to the rotated crc by Mask.
+const maskDelta = 0xa282ead8
+
+// Mask returns a masked representation of crc.
+//
+// Computing the crc of a string that contains embedded crcs is problematic, so LevelDB stores
+// masked crcs. The masking is a rotate right by 15 bits plus a constant.
+func Mask(crc uint32) uint32 {
+	return ((crc >> 15) | (crc << 17)) + maskDelta
+}
+
+// Unmask returns the crc whose masked representation is masked.
+func Unmask(masked uint32) uint32 {
+	rot := masked - maskDelta
+	return (rot >> 17) | (rot << 15)
+}
@@ -0,0 +1,47 @@
+package crc32c
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChecksum_KnownValues(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected uint32
+	}{
+		"32 zero bytes": {input: make([]byte, 32), expected: 0x8a9136aa},
+		"32 0xff bytes": {input: bytes.Repeat([]byte{0xff}, 32), expected: 0x62a8ab43},
+		"123456789":     {input: []byte("123456789"), expected: 0xe3069283},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if actual := Checksum(test.input); actual != test.expected {
+				t.Errorf("Expected %#x but got %#x", test.expected, actual)
+			}
+			h := New()
+			h.Write(test.input)
+			if actual := h.Sum32(); actual != test.expected {
+				t.Errorf("Expected %#x but got %#x", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	crc := Checksum([]byte("foo"))
+
+	if Mask(crc) == crc {
+		t.Errorf("Expected masked checksum to differ from %v", crc)
+	}
+	if Mask(Mask(crc)) == crc {
+		t.Errorf("Expected twice masked checksum to differ from %v", crc)
+	}
+	if actual := Unmask(Mask(crc)); actual != crc {
+		t.Errorf("Expected %v but got %v", crc, actual)
+	}
+	if actual := Unmask(Unmask(Mask(Mask(crc)))); actual != crc {
+		t.Errorf("Expected %v but got %v", crc, actual)
+	}
+}
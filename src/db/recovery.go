@@ -0,0 +1,110 @@
+package db
+
+import (
+	"comparer"
+	"dbformat"
+	"env"
+	"logger"
+	"memtable"
+)
+
+// DefaultWriteBufferSize is the memory usage at which a memtable is flushed unless told otherwise.
+const DefaultWriteBufferSize = 4 << 20
+
+// RecoveryOptions control how RecoverLogs replays the logs of a database.
+type RecoveryOptions struct {
+	// Comparer orders the user keys of the memtable. comparer.BytewiseComparator is used when it is
+	// nil.
+	Comparer comparer.Comparator
+
+	// ParanoidChecks makes any corruption in the logs, damaged fragments as well as malformed write
+	// batches, an error. Otherwise the corrupt parts are skipped and reported to Reader.Reporter.
+	ParanoidChecks bool
+
+	// Reader are the options the logs are read with, for instance the Keys of encrypted logs. Its
+	// Reporter is only used when ParanoidChecks is not set.
+	Reader logger.ReaderOptions
+
+	// WriteBufferSize is the memory usage above which the memtable is handed to Flush, and replay goes
+	// on with a new memtable. DefaultWriteBufferSize is used when it is zero.
+	WriteBufferSize int64
+
+	// Flush writes a full memtable out, typically to a sorted table. When it is nil, the memtable is
+	// never flushed during replay, however large it grows.
+	Flush func(m *memtable.MemTable) error
+}
+
+// RecoveryResult is the state of a database that RecoverLogs rebuilt from its logs.
+type RecoveryResult struct {
+	// MemTable holds the updates replayed since the last flush.
+	MemTable *memtable.MemTable
+	// LastSequence is the sequence number of the last update found in the logs, or zero when there
+	// was none.
+	LastSequence dbformat.SequenceNumber
+	// Flushes counts the memtables handed to Flush during replay.
+	Flushes int
+}
+
+// RecoverLogs replays the write batches in the logs in dir on fs, as written by a logger.LogManager, into
+// a new memtable, in log number order.
+//
+// A record cut short at the end of the last log is what a crash while writing leaves behind, and is
+// never an error. Any other corruption fails the recovery when ParanoidChecks is set, and is skipped
+// otherwise.
+func RecoverLogs(fs env.FS, dir string, opts RecoveryOptions) (RecoveryResult, error) {
+	if opts.Comparer == nil {
+		opts.Comparer = comparer.BytewiseComparator
+	}
+	if opts.WriteBufferSize == 0 {
+		opts.WriteBufferSize = DefaultWriteBufferSize
+	}
+
+	readerOpts := opts.Reader
+	reporter := readerOpts.Reporter
+	if opts.ParanoidChecks {
+		readerOpts.Reporter = nil
+	} else if reporter == nil {
+		reporter = discardReporter{}
+		readerOpts.Reporter = reporter
+	}
+	mr, err := logger.NewMultiReader(fs, dir, readerOpts)
+	if err != nil {
+		return RecoveryResult{}, err
+	}
+	defer mr.Close()
+
+	result := RecoveryResult{MemTable: memtable.New(opts.Comparer)}
+	var batch WriteBatch
+	for mr.Next() {
+		record := mr.Record()
+		err := batch.SetContents(record)
+		if err == nil {
+			err = batch.InsertInto(result.MemTable)
+			// The header is intact even when the updates are not, and the updates of a malformed
+			// batch before the damage are kept, as LevelDB does. So their sequence numbers are used.
+			if last := batch.Sequence() + dbformat.SequenceNumber(batch.Count()) - 1; batch.Count() > 0 && last > result.LastSequence {
+				result.LastSequence = last
+			}
+		}
+		if err != nil {
+			if opts.ParanoidChecks {
+				return result, err
+			}
+			reporter.Corruption(len(record), err)
+			continue
+		}
+
+		if opts.Flush != nil && result.MemTable.ApproximateMemoryUsage() > opts.WriteBufferSize {
+			if err := opts.Flush(result.MemTable); err != nil {
+				return result, err
+			}
+			result.Flushes++
+			result.MemTable = memtable.New(opts.Comparer)
+		}
+	}
+	return result, mr.Err()
+}
+
+type discardReporter struct{}
+
+func (discardReporter) Corruption(bytes int, reason error) {}
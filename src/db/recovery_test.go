@@ -0,0 +1,202 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"dbformat"
+	"env"
+	"logger"
+	"memtable"
+)
+
+// writeBatches logs batches to the directory "db" of fs, numbering their updates from 1 on, and
+// starts a new log before each batch in rollBefore.
+func writeBatches(t *testing.T, fs env.FS, batches []*WriteBatch, rollBefore ...int) {
+	m, err := logger.OpenLogManager(fs, "db", logger.LogManagerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := dbformat.SequenceNumber(1)
+	for i, b := range batches {
+		for _, r := range rollBefore {
+			if r == i {
+				if _, err := m.Roll(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		b.SetSequence(seq)
+		seq += dbformat.SequenceNumber(b.Count())
+		if _, _, err := m.Write(b.Contents(), logger.WriteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func batchOf(updates ...string) *WriteBatch {
+	b := &WriteBatch{}
+	for i := 0; i < len(updates); i += 2 {
+		if updates[i+1] == "" {
+			b.Delete([]byte(updates[i]))
+		} else {
+			b.Put([]byte(updates[i]), []byte(updates[i+1]))
+		}
+	}
+	return b
+}
+
+func get(m *memtable.MemTable, key string) string {
+	value, result := m.Get(dbformat.NewLookupKey([]byte(key), dbformat.MaxSequenceNumber))
+	switch result {
+	case memtable.Found:
+		return string(value)
+	case memtable.Deleted:
+		return "DELETED"
+	}
+	return "NOT_FOUND"
+}
+
+type countingReporter struct {
+	reasons []error
+}
+
+func (r *countingReporter) Corruption(bytes int, reason error) {
+	r.reasons = append(r.reasons, reason)
+}
+
+func TestRecoverLogs_ShouldReplayEveryLog(t *testing.T) {
+	fs := env.NewMemFS()
+	writeBatches(t, fs, []*WriteBatch{
+		batchOf("a", "1", "b", "2"),
+		batchOf("c", "3"),
+		batchOf("a", "4", "b", ""),
+	}, 0, 2)
+
+	result, err := RecoverLogs(fs, "db", RecoveryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"a": "4", "b": "DELETED", "c": "3", "d": "NOT_FOUND"}
+	for key, value := range expected {
+		if actual := get(result.MemTable, key); actual != value {
+			t.Errorf("Expected %v for %v but got %v", value, key, actual)
+		}
+	}
+	if result.LastSequence != 5 {
+		t.Errorf("Expected last sequence 5 but got %v", result.LastSequence)
+	}
+}
+
+func TestRecoverLogs_WithoutLogs(t *testing.T) {
+	fs := env.NewMemFS()
+	fs.MkdirAll("db")
+
+	result, err := RecoverLogs(fs, "db", RecoveryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MemTable == nil || result.LastSequence != 0 {
+		t.Errorf("Expected an empty memtable at sequence 0 but got sequence %v", result.LastSequence)
+	}
+}
+
+func TestRecoverLogs_ShouldFlushFullMemTables(t *testing.T) {
+	fs := env.NewMemFS()
+	var batches []*WriteBatch
+	for i := 0; i < 100; i++ {
+		batches = append(batches, batchOf(fmt.Sprintf("key%03d", i), string(make([]byte, 1000))))
+	}
+	writeBatches(t, fs, batches)
+
+	var flushed []*memtable.MemTable
+	result, err := RecoverLogs(fs, "db", RecoveryOptions{
+		WriteBufferSize: 10000,
+		Flush: func(m *memtable.MemTable) error {
+			flushed = append(flushed, m)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Flushes != len(flushed) || len(flushed) < 5 {
+		t.Errorf("Expected several flushes but got %v, %v reported", len(flushed), result.Flushes)
+	}
+	entries := 0
+	for _, m := range append(flushed, result.MemTable) {
+		it := m.NewIterator()
+		for it.SeekToFirst(); it.Valid(); it.Next() {
+			entries++
+		}
+	}
+	if entries != 100 {
+		t.Errorf("Expected 100 entries but got %v", entries)
+	}
+	if result.LastSequence != 100 {
+		t.Errorf("Expected last sequence 100 but got %v", result.LastSequence)
+	}
+}
+
+func TestRecoverLogs_ParanoidChecks(t *testing.T) {
+	tests := map[string]func(t *testing.T, fs env.FS){
+		"Malformed batch": func(t *testing.T, fs env.FS) {
+			malformed := batchOf("b", "2")
+			malformed.setCount(2)
+			writeBatches(t, fs, []*WriteBatch{batchOf("a", "1"), malformed, batchOf("c", "3")})
+		},
+		"Damaged log": func(t *testing.T, fs env.FS) {
+			faulty := env.NewFaultyFS(fs, 1)
+			writeBatches(t, faulty, []*WriteBatch{batchOf("a", "1"), batchOf("c", "3")}, 1)
+			if err := faulty.FlipBits(logger.LogFileName("db", 1), 1); err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+
+	for testName, damage := range tests {
+		t.Run(testName, func(t *testing.T) {
+			fs := env.NewMemFS()
+			damage(t, fs)
+
+			if _, err := RecoverLogs(fs, "db", RecoveryOptions{ParanoidChecks: true}); err == nil {
+				t.Error("Expected an error with paranoid checks but got none")
+			}
+
+			reporter := &countingReporter{}
+			result, err := RecoverLogs(fs, "db", RecoveryOptions{Reader: logger.ReaderOptions{Reporter: reporter}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reporter.reasons) == 0 {
+				t.Error("Expected the corruption to be reported but it was not")
+			}
+			if actual := get(result.MemTable, "c"); actual != "3" {
+				t.Errorf("Expected the batch after the corruption to be replayed but got %v", actual)
+			}
+		})
+	}
+}
+
+func TestRecoverLogs_ShouldCountTheSequenceNumbersOfAMalformedBatch(t *testing.T) {
+	fs := env.NewMemFS()
+	malformed := batchOf("b", "2")
+	malformed.setCount(2)
+	writeBatches(t, fs, []*WriteBatch{batchOf("a", "1"), malformed})
+
+	result, err := RecoverLogs(fs, "db", RecoveryOptions{Reader: logger.ReaderOptions{Reporter: &countingReporter{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := get(result.MemTable, "b"); actual != "2" {
+		t.Errorf("Expected the update before the damage to be replayed but got %v", actual)
+	}
+	if result.LastSequence != 3 {
+		t.Errorf("Expected last sequence 3 but got %v", result.LastSequence)
+	}
+}
@@ -0,0 +1,221 @@
+// Package db is the key-value store built on the log, the memtable and sorted tables.
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"dbformat"
+	"logger"
+	"memtable"
+)
+
+// writeBatchHeaderSize is the size of the sequence number and count that start every write batch.
+const writeBatchHeaderSize = 12
+
+var (
+	errorBatchTooSmall   = errors.New("db: malformed write batch (too small)")
+	errorBadBatchPut     = errors.New("db: bad write batch Put")
+	errorBadBatchDelete  = errors.New("db: bad write batch Delete")
+	errorUnknownBatchTag = errors.New("db: unknown write batch tag")
+	errorWrongBatchCount = errors.New("db: write batch has wrong count")
+)
+
+// WriteBatch holds updates that are applied to a database atomically, in the order they were added.
+// Its contents use LevelDB's encoding, which is what a write batch is logged as:
+//
+//	batch := sequence: fixed64, count: fixed32, entry[count]
+//	entry :=
+//	  TypeValue varstring varstring   // Put of a key and a value
+//	  TypeDeletion varstring          // Delete of a key
+//	varstring := length: varint32, data: uint8[length]
+//
+// The fixed width integers are little-endian. The zero value is an empty batch ready to use.
+type WriteBatch struct {
+	rep []byte
+}
+
+// Handler receives the updates of a WriteBatch from Iterate.
+type Handler interface {
+	Put(key, value []byte)
+	Delete(key []byte)
+}
+
+func (b *WriteBatch) init() {
+	if len(b.rep) < writeBatchHeaderSize {
+		b.rep = make([]byte, writeBatchHeaderSize)
+	}
+}
+
+// Put adds an update that sets key to value.
+func (b *WriteBatch) Put(key, value []byte) {
+	b.init()
+	b.setCount(b.Count() + 1)
+	b.rep = append(b.rep, byte(dbformat.TypeValue))
+	b.rep = appendVarstring(b.rep, key)
+	b.rep = appendVarstring(b.rep, value)
+}
+
+// Delete adds an update that deletes key.
+func (b *WriteBatch) Delete(key []byte) {
+	b.init()
+	b.setCount(b.Count() + 1)
+	b.rep = append(b.rep, byte(dbformat.TypeDeletion))
+	b.rep = appendVarstring(b.rep, key)
+}
+
+// Clear removes every update from the batch and resets its sequence number.
+func (b *WriteBatch) Clear() {
+	b.rep = b.rep[:0]
+	b.init()
+	for i := range b.rep {
+		b.rep[i] = 0
+	}
+}
+
+// Append adds the updates of src to the batch, after its own.
+func (b *WriteBatch) Append(src *WriteBatch) {
+	b.init()
+	if len(src.rep) <= writeBatchHeaderSize {
+		return
+	}
+	b.setCount(b.Count() + src.Count())
+	b.rep = append(b.rep, src.rep[writeBatchHeaderSize:]...)
+}
+
+// Count returns the number of updates in the batch.
+func (b *WriteBatch) Count() int {
+	if len(b.rep) < writeBatchHeaderSize {
+		return 0
+	}
+	return int(binary.LittleEndian.Uint32(b.rep[8:]))
+}
+
+func (b *WriteBatch) setCount(n int) {
+	binary.LittleEndian.PutUint32(b.rep[8:], uint32(n))
+}
+
+// Sequence returns the sequence number of the first update in the batch.
+func (b *WriteBatch) Sequence() dbformat.SequenceNumber {
+	if len(b.rep) < writeBatchHeaderSize {
+		return 0
+	}
+	return dbformat.SequenceNumber(binary.LittleEndian.Uint64(b.rep))
+}
+
+// SetSequence sets the sequence number of the first update in the batch. The updates after it get the
+// numbers that follow.
+func (b *WriteBatch) SetSequence(seq dbformat.SequenceNumber) {
+	b.init()
+	binary.LittleEndian.PutUint64(b.rep, uint64(seq))
+}
+
+// Contents returns the encoded batch. It is only valid until the batch is changed.
+func (b *WriteBatch) Contents() []byte {
+	b.init()
+	return b.rep
+}
+
+// SetContents replaces the batch with the encoded batch data, as returned by Contents. The entries are
+// only checked by Iterate. The batch keeps a copy of data.
+func (b *WriteBatch) SetContents(data []byte) error {
+	if len(data) < writeBatchHeaderSize {
+		return errorBatchTooSmall
+	}
+	b.rep = append(b.rep[:0], data...)
+	return nil
+}
+
+// ApproximateSize returns the size of the encoded batch.
+func (b *WriteBatch) ApproximateSize() int {
+	b.init()
+	return len(b.rep)
+}
+
+// Iterate passes the updates of the batch to h in order. It returns an error, after passing the updates
+// before it, when the batch is malformed or holds a different number of updates than its count.
+func (b *WriteBatch) Iterate(h Handler) error {
+	if len(b.rep) < writeBatchHeaderSize {
+		if len(b.rep) == 0 {
+			return nil
+		}
+		return errorBatchTooSmall
+	}
+
+	input := b.rep[writeBatchHeaderSize:]
+	found := 0
+	for len(input) > 0 {
+		found++
+		tag := dbformat.ValueType(input[0])
+		input = input[1:]
+		switch tag {
+		case dbformat.TypeValue:
+			key, rest, ok := readVarstring(input)
+			if !ok {
+				return errorBadBatchPut
+			}
+			value, rest, ok := readVarstring(rest)
+			if !ok {
+				return errorBadBatchPut
+			}
+			input = rest
+			h.Put(key, value)
+		case dbformat.TypeDeletion:
+			key, rest, ok := readVarstring(input)
+			if !ok {
+				return errorBadBatchDelete
+			}
+			input = rest
+			h.Delete(key)
+		default:
+			return errorUnknownBatchTag
+		}
+	}
+	if found != b.Count() {
+		return errorWrongBatchCount
+	}
+	return nil
+}
+
+// InsertInto adds the updates of the batch to m, numbered from the sequence number of the batch on.
+func (b *WriteBatch) InsertInto(m *memtable.MemTable) error {
+	return b.Iterate(&memTableInserter{seq: b.Sequence(), mem: m})
+}
+
+// memTableInserter is the Handler that adds the updates of a batch to a memtable.
+type memTableInserter struct {
+	seq dbformat.SequenceNumber
+	mem *memtable.MemTable
+}
+
+func (h *memTableInserter) Put(key, value []byte) {
+	h.mem.Add(h.seq, dbformat.TypeValue, key, value)
+	h.seq++
+}
+
+func (h *memTableInserter) Delete(key []byte) {
+	h.mem.Add(h.seq, dbformat.TypeDeletion, key, nil)
+	h.seq++
+}
+
+// WriteToLog writes the batch as one record to w.
+func (b *WriteBatch) WriteToLog(w *logger.RecordWriter, opts logger.WriteOptions) error {
+	_, err := w.WriteWithOptions(b.Contents(), opts)
+	return err
+}
+
+func appendVarstring(dst, s []byte) []byte {
+	var buf [binary.MaxVarintLen32]byte
+	n := binary.PutUvarint(buf[:], uint64(len(s)))
+	return append(append(dst, buf[:n]...), s...)
+}
+
+// readVarstring returns the string at the start of input and what follows it.
+func readVarstring(input []byte) ([]byte, []byte, bool) {
+	length, n := binary.Uvarint(input)
+	if n <= 0 || length > 1<<32-1 || uint64(len(input)-n) < length {
+		return nil, nil, false
+	}
+	end := n + int(length)
+	return input[n:end], input[end:], true
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"comparer"
+	"dbformat"
+	"env"
+	"logger"
+	"memtable"
+)
+
+// recordingHandler records the updates of a batch as strings like "Put(key, value)".
+type recordingHandler struct {
+	updates []string
+}
+
+func (h *recordingHandler) Put(key, value []byte) {
+	h.updates = append(h.updates, fmt.Sprintf("Put(%s, %s)", key, value))
+}
+
+func (h *recordingHandler) Delete(key []byte) {
+	h.updates = append(h.updates, fmt.Sprintf("Delete(%s)", key))
+}
+
+func iterate(t *testing.T, b *WriteBatch) []string {
+	h := &recordingHandler{}
+	if err := b.Iterate(h); err != nil {
+		t.Fatal(err)
+	}
+	return h.updates
+}
+
+func TestWriteBatch_Empty(t *testing.T) {
+	var b WriteBatch
+
+	if b.Count() != 0 {
+		t.Errorf("Expected 0 updates but got %v", b.Count())
+	}
+	if updates := iterate(t, &b); len(updates) != 0 {
+		t.Errorf("Expected no updates but got %v", updates)
+	}
+	if expected := make([]byte, writeBatchHeaderSize); !bytes.Equal(b.Contents(), expected) {
+		t.Errorf("Expected %v but got %v", expected, b.Contents())
+	}
+}
+
+func TestWriteBatch_ShouldUseLevelDBEncoding(t *testing.T) {
+	var b WriteBatch
+	b.Put([]byte("foo"), []byte("bar"))
+	b.Delete([]byte("box"))
+	b.SetSequence(100)
+
+	expected := []byte{
+		100, 0, 0, 0, 0, 0, 0, 0, // sequence
+		2, 0, 0, 0, // count
+		1, 3, 'f', 'o', 'o', 3, 'b', 'a', 'r',
+		0, 3, 'b', 'o', 'x',
+	}
+	if !bytes.Equal(b.Contents(), expected) {
+		t.Errorf("Expected %v but got %v", expected, b.Contents())
+	}
+	if b.Sequence() != 100 {
+		t.Errorf("Expected sequence 100 but got %v", b.Sequence())
+	}
+	if b.ApproximateSize() != len(expected) {
+		t.Errorf("Expected size %v but got %v", len(expected), b.ApproximateSize())
+	}
+}
+
+func TestWriteBatch_Iterate(t *testing.T) {
+	var b WriteBatch
+	b.Put([]byte("foo"), []byte("bar"))
+	b.Delete([]byte("box"))
+	b.Put([]byte("baz"), []byte("boo"))
+	b.Put([]byte("long"), bytes.Repeat([]byte("v"), 200))
+
+	expected := []string{"Put(foo, bar)", "Delete(box)", "Put(baz, boo)", "Put(long, " + string(bytes.Repeat([]byte("v"), 200)) + ")"}
+	if updates := iterate(t, &b); !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %v but got %v", expected, updates)
+	}
+	if b.Count() != 4 {
+		t.Errorf("Expected 4 updates but got %v", b.Count())
+	}
+}
+
+func TestWriteBatch_Clear(t *testing.T) {
+	var b WriteBatch
+	b.Put([]byte("foo"), []byte("bar"))
+	b.SetSequence(7)
+	b.Clear()
+
+	if b.Count() != 0 || b.Sequence() != 0 {
+		t.Errorf("Expected an empty batch but got count %v and sequence %v", b.Count(), b.Sequence())
+	}
+	if updates := iterate(t, &b); len(updates) != 0 {
+		t.Errorf("Expected no updates but got %v", updates)
+	}
+}
+
+func TestWriteBatch_Append(t *testing.T) {
+	var b1, b2 WriteBatch
+	b1.SetSequence(200)
+	b2.SetSequence(300)
+	b1.Append(&b2)
+	if b1.Count() != 0 {
+		t.Errorf("Expected 0 updates but got %v", b1.Count())
+	}
+
+	b2.Put([]byte("a"), []byte("va"))
+	b1.Append(&b2)
+	b2.Clear()
+	b2.Put([]byte("b"), []byte("vb"))
+	b2.Delete([]byte("foo"))
+	b1.Append(&b2)
+
+	expected := []string{"Put(a, va)", "Put(b, vb)", "Delete(foo)"}
+	if updates := iterate(t, &b1); !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %v but got %v", expected, updates)
+	}
+	if b1.Count() != 3 || b1.Sequence() != 200 {
+		t.Errorf("Expected 3 updates at sequence 200 but got %v at %v", b1.Count(), b1.Sequence())
+	}
+}
+
+func TestWriteBatch_Iterate_ShouldFailOnMalformedBatches(t *testing.T) {
+	var valid WriteBatch
+	valid.Put([]byte("foo"), []byte("bar"))
+	contents := valid.Contents()
+
+	wrongCount := append([]byte(nil), contents...)
+	wrongCount[8] = 2
+	unknownTag := append(append([]byte(nil), contents...), 0x7)
+	unknownTag[8] = 2
+	badDelete := append(append([]byte(nil), contents...), byte(dbformat.TypeDeletion), 5, 'a')
+	badDelete[8] = 2
+
+	tests := map[string]struct {
+		contents []byte
+		err      error
+	}{
+		"Truncated Put":  {contents[:len(contents)-1], errorBadBatchPut},
+		"Wrong count":    {wrongCount, errorWrongBatchCount},
+		"Unknown tag":    {unknownTag, errorUnknownBatchTag},
+		"Bad Delete":     {badDelete, errorBadBatchDelete},
+		"Missing length": {append(append([]byte(nil), contents[:writeBatchHeaderSize]...), byte(dbformat.TypeValue)), errorBadBatchPut},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var b WriteBatch
+			if err := b.SetContents(test.contents); err != nil {
+				t.Fatal(err)
+			}
+			if err := b.Iterate(&recordingHandler{}); err != test.err {
+				t.Errorf("Expected '%v' but got '%v'", test.err, err)
+			}
+		})
+	}
+}
+
+func TestWriteBatch_SetContents_ShouldRejectShortContents(t *testing.T) {
+	var b WriteBatch
+	if err := b.SetContents(make([]byte, writeBatchHeaderSize-1)); err != errorBatchTooSmall {
+		t.Errorf("Expected '%v' but got '%v'", errorBatchTooSmall, err)
+	}
+}
+
+func TestWriteBatch_InsertInto(t *testing.T) {
+	var b WriteBatch
+	b.Put([]byte("foo"), []byte("bar"))
+	b.Delete([]byte("box"))
+	b.Put([]byte("baz"), []byte("boo"))
+	b.SetSequence(100)
+
+	m := memtable.New(comparer.BytewiseComparator)
+	if err := b.InsertInto(m); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []string
+	it := m.NewIterator()
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		k, _ := dbformat.ParseInternalKey(it.Key())
+		entries = append(entries, fmt.Sprintf("%v=%s", k, it.Value()))
+	}
+	expected := []string{"'baz' @ 102 : Value=boo", "'box' @ 101 : Deletion=", "'foo' @ 100 : Value=bar"}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %v but got %v", expected, entries)
+	}
+}
+
+func TestWriteBatch_ShouldReplayFromTheLog(t *testing.T) {
+	var first, second WriteBatch
+	first.Put([]byte("foo"), []byte("bar"))
+	first.Delete([]byte("box"))
+	first.SetSequence(1)
+	second.Put([]byte("big"), bytes.Repeat([]byte("x"), 40000))
+	second.SetSequence(3)
+
+	fs := env.NewMemFS()
+	f, err := fs.Create("000001.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := logger.NewRecordWriter(f, 0)
+	for _, b := range []*WriteBatch{&first, &second} {
+		if err := b.WriteToLog(w, logger.WriteOptions{Sync: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	if f, err = fs.Open("000001.log"); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rr := logger.NewRecordReader(f, 0)
+	var replayed []string
+	var sequences []dbformat.SequenceNumber
+	for rr.Next() {
+		var b WriteBatch
+		if err := b.SetContents(rr.Record()); err != nil {
+			t.Fatal(err)
+		}
+		sequences = append(sequences, b.Sequence())
+		replayed = append(replayed, iterate(t, &b)...)
+	}
+	if rr.Err() != nil {
+		t.Fatal(rr.Err())
+	}
+
+	expected := append(iterate(t, &first), iterate(t, &second)...)
+	if !reflect.DeepEqual(replayed, expected) {
+		t.Error("Expected the replayed updates to be the updates written but was not")
+	}
+	if expectedSequences := []dbformat.SequenceNumber{1, 3}; !reflect.DeepEqual(sequences, expectedSequences) {
+		t.Errorf("Expected %v but got %v", expectedSequences, sequences)
+	}
+}
@@ -0,0 +1,31 @@
+// Package dbformat defines the types that make up LevelDB's internal keys, which order the versions of
+// a user key in the memtable and in sorted tables.
+package dbformat
+
+import "fmt"
+
+// SequenceNumber orders the updates to a database. Every update in a write batch gets the next one.
+type SequenceNumber uint64
+
+// MaxSequenceNumber is the largest sequence number, which leaves the low byte of the 64 bit internal key
+// trailer for the ValueType.
+const MaxSequenceNumber SequenceNumber = 1<<56 - 1
+
+// ValueType tells whether an update sets a key to a value or deletes it. The values are part of the on
+// disk format of write batches and internal keys.
+type ValueType byte
+
+const (
+	TypeDeletion ValueType = 0x0
+	TypeValue    ValueType = 0x1
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case TypeDeletion:
+		return "Deletion"
+	case TypeValue:
+		return "Value"
+	}
+	return fmt.Sprintf("ValueType(%d)", byte(t))
+}
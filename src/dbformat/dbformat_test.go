@@ -0,0 +1,19 @@
+package dbformat
+
+import "testing"
+
+func TestValueType_String(t *testing.T) {
+	tests := map[ValueType]string{
+		TypeDeletion:   "Deletion",
+		TypeValue:      "Value",
+		ValueType(0x7): "ValueType(7)",
+	}
+
+	for valueType, expected := range tests {
+		t.Run(expected, func(t *testing.T) {
+			if actual := valueType.String(); actual != expected {
+				t.Errorf("Expected %v but got %v", expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package dbformat
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"comparer"
+	"filter"
+)
+
+// ValueTypeForSeek is the type to build an internal key to seek to with. Internal keys of the same user
+// key and sequence number are ordered by decreasing type, so it must be the highest type.
+const ValueTypeForSeek = TypeValue
+
+// internalKeyTrailerSize is the size of the sequence number and type that end an internal key.
+const internalKeyTrailerSize = 8
+
+// An internal key is a user key followed by a little-endian 64 bit trailer that packs the sequence
+// number, in the high 56 bits, with the ValueType, in the low 8 bits:
+//
+//	internal key := user key: uint8[*], trailer: fixed64
+//	trailer := sequence << 8 | type
+
+// ParsedInternalKey is an internal key split into its parts.
+type ParsedInternalKey struct {
+	UserKey  []byte
+	Sequence SequenceNumber
+	Type     ValueType
+}
+
+func (k ParsedInternalKey) String() string {
+	return fmt.Sprintf("'%s' @ %d : %v", k.UserKey, k.Sequence, k.Type)
+}
+
+func packTrailer(seq SequenceNumber, t ValueType) uint64 {
+	return uint64(seq)<<8 | uint64(t)
+}
+
+// AppendInternalKey appends the internal key of userKey, seq and t to dst.
+func AppendInternalKey(dst, userKey []byte, seq SequenceNumber, t ValueType) []byte {
+	var trailer [internalKeyTrailerSize]byte
+	binary.LittleEndian.PutUint64(trailer[:], packTrailer(seq, t))
+	return append(append(dst, userKey...), trailer[:]...)
+}
+
+// ParseInternalKey splits an internal key into its parts. It returns false when the key is too short to
+// be an internal key or has an unknown type.
+func ParseInternalKey(internalKey []byte) (ParsedInternalKey, bool) {
+	n := len(internalKey) - internalKeyTrailerSize
+	if n < 0 {
+		return ParsedInternalKey{}, false
+	}
+	trailer := binary.LittleEndian.Uint64(internalKey[n:])
+	k := ParsedInternalKey{internalKey[:n], SequenceNumber(trailer >> 8), ValueType(trailer)}
+	return k, k.Type <= TypeValue
+}
+
+// ExtractUserKey returns the user key of an internal key, which must be at least 8 bytes long.
+func ExtractUserKey(internalKey []byte) []byte {
+	return internalKey[:len(internalKey)-internalKeyTrailerSize]
+}
+
+// InternalKeyComparator orders internal keys by increasing user key, as ordered by User, and then by
+// decreasing sequence number and type, so that the newest update of a user key comes first.
+type InternalKeyComparator struct {
+	User comparer.Comparator
+}
+
+func (c InternalKeyComparator) Compare(a, b []byte) int {
+	if r := c.User.Compare(ExtractUserKey(a), ExtractUserKey(b)); r != 0 {
+		return r
+	}
+	trailerA := binary.LittleEndian.Uint64(a[len(a)-internalKeyTrailerSize:])
+	trailerB := binary.LittleEndian.Uint64(b[len(b)-internalKeyTrailerSize:])
+	switch {
+	case trailerA > trailerB:
+		return -1
+	case trailerA < trailerB:
+		return 1
+	}
+	return 0
+}
+
+func (c InternalKeyComparator) Name() string {
+	return "leveldb.InternalKeyComparator"
+}
+
+// FindShortestSeparator shortens the user key of start when the user comparator can, and gives the
+// result the earliest possible trailer, so that it still sorts before limit.
+func (c InternalKeyComparator) FindShortestSeparator(start, limit []byte) []byte {
+	userStart, userLimit := ExtractUserKey(start), ExtractUserKey(limit)
+	separator := c.User.FindShortestSeparator(userStart, userLimit)
+	if len(separator) < len(userStart) && c.User.Compare(userStart, separator) < 0 {
+		return AppendInternalKey(separator, nil, MaxSequenceNumber, ValueTypeForSeek)
+	}
+	return append([]byte(nil), start...)
+}
+
+// FindShortSuccessor shortens the user key of key when the user comparator can, and gives the result
+// the earliest possible trailer.
+func (c InternalKeyComparator) FindShortSuccessor(key []byte) []byte {
+	userKey := ExtractUserKey(key)
+	successor := c.User.FindShortSuccessor(userKey)
+	if len(successor) < len(userKey) && c.User.Compare(userKey, successor) < 0 {
+		return AppendInternalKey(successor, nil, MaxSequenceNumber, ValueTypeForSeek)
+	}
+	return append([]byte(nil), key...)
+}
+
+// InternalFilterPolicy is a filter policy for tables of internal keys. It filters on the user keys, so
+// that a lookup of any version of a user key matches.
+type InternalFilterPolicy struct {
+	User filter.FilterPolicy
+}
+
+// Name is the name of the user policy, as the filters are the same.
+func (p InternalFilterPolicy) Name() string {
+	return p.User.Name()
+}
+
+func (p InternalFilterPolicy) AppendFilter(dst []byte, keys [][]byte) []byte {
+	userKeys := make([][]byte, len(keys))
+	for i, key := range keys {
+		userKeys[i] = ExtractUserKey(key)
+	}
+	return p.User.AppendFilter(dst, userKeys)
+}
+
+func (p InternalFilterPolicy) KeyMayMatch(key, filter []byte) bool {
+	return p.User.KeyMayMatch(ExtractUserKey(key), filter)
+}
+
+// LookupKey is the key that a read of a user key at a sequence number looks for in the memtable. It
+// finds the newest update of the user key at or before the sequence number.
+//
+//	lookup key := length: varint32, internal key: uint8[length]
+type LookupKey struct {
+	data []byte
+	// start is where the internal key starts in data.
+	start int
+}
+
+// NewLookupKey creates the key that looks for userKey as of seq.
+func NewLookupKey(userKey []byte, seq SequenceNumber) LookupKey {
+	var length [binary.MaxVarintLen32]byte
+	n := binary.PutUvarint(length[:], uint64(len(userKey)+internalKeyTrailerSize))
+	data := make([]byte, 0, n+len(userKey)+internalKeyTrailerSize)
+	data = AppendInternalKey(append(data, length[:n]...), userKey, seq, ValueTypeForSeek)
+	return LookupKey{data, n}
+}
+
+// MemtableKey returns the key to search the memtable with.
+func (k LookupKey) MemtableKey() []byte {
+	return k.data
+}
+
+// InternalKey returns the internal key to search sorted tables with.
+func (k LookupKey) InternalKey() []byte {
+	return k.data[k.start:]
+}
+
+// UserKey returns the user key that is looked for.
+func (k LookupKey) UserKey() []byte {
+	return k.data[k.start : len(k.data)-internalKeyTrailerSize]
+}
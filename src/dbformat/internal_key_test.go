@@ -0,0 +1,136 @@
+package dbformat
+
+import (
+	"bytes"
+	"testing"
+
+	"comparer"
+	"filter"
+)
+
+func TestInternalKey_RoundTrip(t *testing.T) {
+	tests := map[string]ParsedInternalKey{
+		"Empty user key":       {[]byte(""), 1, TypeValue},
+		"Deletion":             {[]byte("foo"), 100, TypeDeletion},
+		"Max sequence number":  {[]byte("bar"), MaxSequenceNumber, TypeValue},
+		"Sequence number of 0": {[]byte("hello"), 0, TypeDeletion},
+		"Key with high bytes":  {[]byte("\xff\xff"), 1 << 40, TypeValue},
+	}
+
+	for testName, key := range tests {
+		t.Run(testName, func(t *testing.T) {
+			internalKey := AppendInternalKey(nil, key.UserKey, key.Sequence, key.Type)
+			if len(internalKey) != len(key.UserKey)+8 {
+				t.Errorf("Expected %v bytes but got %v", len(key.UserKey)+8, len(internalKey))
+			}
+
+			parsed, ok := ParseInternalKey(internalKey)
+			if !ok {
+				t.Fatal("Expected the key to parse but it did not")
+			}
+			if !bytes.Equal(parsed.UserKey, key.UserKey) || parsed.Sequence != key.Sequence || parsed.Type != key.Type {
+				t.Errorf("Expected %v but got %v", key, parsed)
+			}
+			if !bytes.Equal(ExtractUserKey(internalKey), key.UserKey) {
+				t.Errorf("Expected user key %q but got %q", key.UserKey, ExtractUserKey(internalKey))
+			}
+		})
+	}
+}
+
+func TestParseInternalKey_ShouldRejectBadKeys(t *testing.T) {
+	tests := map[string][]byte{
+		"Too short":    []byte("1234567"),
+		"Unknown type": AppendInternalKey(nil, []byte("foo"), 1, ValueType(0x2)),
+	}
+
+	for testName, internalKey := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if _, ok := ParseInternalKey(internalKey); ok {
+				t.Error("Expected the key not to parse but it did")
+			}
+		})
+	}
+}
+
+func TestInternalKeyComparator_Compare(t *testing.T) {
+	ikey := func(userKey string, seq SequenceNumber, vt ValueType) []byte {
+		return AppendInternalKey(nil, []byte(userKey), seq, vt)
+	}
+	tests := map[string]struct {
+		a, b     []byte
+		expected int
+	}{
+		"User keys first":         {ikey("a", 1, TypeValue), ikey("b", 100, TypeValue), -1},
+		"Newer sequence first":    {ikey("a", 100, TypeValue), ikey("a", 1, TypeValue), -1},
+		"Older sequence last":     {ikey("a", 1, TypeValue), ikey("a", 100, TypeValue), 1},
+		"Value before deletion":   {ikey("a", 5, TypeValue), ikey("a", 5, TypeDeletion), -1},
+		"Equal":                   {ikey("a", 5, TypeValue), ikey("a", 5, TypeValue), 0},
+		"Prefix user key is less": {ikey("a", 1, TypeValue), ikey("ab", 100, TypeValue), -1},
+	}
+
+	c := InternalKeyComparator{comparer.BytewiseComparator}
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if actual := c.Compare(test.a, test.b); actual != test.expected {
+				t.Errorf("Expected %v but got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestInternalKeyComparator_FindShortestSeparatorAndSuccessor(t *testing.T) {
+	ikey := func(userKey string, seq SequenceNumber) []byte {
+		return AppendInternalKey(nil, []byte(userKey), seq, TypeValue)
+	}
+	c := InternalKeyComparator{comparer.BytewiseComparator}
+
+	tests := map[string]struct {
+		actual, expected []byte
+	}{
+		"Separator of equal user keys":      {c.FindShortestSeparator(ikey("foo", 100), ikey("foo", 99)), ikey("foo", 100)},
+		"Separator of misordered user keys": {c.FindShortestSeparator(ikey("foo", 100), ikey("bar", 99)), ikey("foo", 100)},
+		"Separator shortens the user key":   {c.FindShortestSeparator(ikey("foo", 100), ikey("hello", 200)), AppendInternalKey(nil, []byte("g"), MaxSequenceNumber, ValueTypeForSeek)},
+		"Separator of a prefix user key":    {c.FindShortestSeparator(ikey("foo", 100), ikey("foobar", 200)), ikey("foo", 100)},
+		"Successor shortens the user key":   {c.FindShortSuccessor(ikey("foo", 100)), AppendInternalKey(nil, []byte("g"), MaxSequenceNumber, ValueTypeForSeek)},
+		"Successor of 0xff user key":        {c.FindShortSuccessor(ikey("\xff\xff", 100)), ikey("\xff\xff", 100)},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if !bytes.Equal(test.actual, test.expected) {
+				t.Errorf("Expected %q but got %q", test.expected, test.actual)
+			}
+		})
+	}
+}
+
+func TestInternalFilterPolicy_ShouldFilterOnUserKeys(t *testing.T) {
+	p := InternalFilterPolicy{filter.NewBloomFilterPolicy(10)}
+	f := p.AppendFilter(nil, [][]byte{AppendInternalKey(nil, []byte("foo"), 1, TypeValue)})
+
+	if !p.KeyMayMatch(AppendInternalKey(nil, []byte("foo"), 100, ValueTypeForSeek), f) {
+		t.Error("Expected a newer version of the user key to match but it did not")
+	}
+	if p.KeyMayMatch(AppendInternalKey(nil, []byte("bar"), 1, TypeValue), f) {
+		t.Error("Expected another user key not to match but it did")
+	}
+	if p.Name() != "leveldb.BuiltinBloomFilter2" {
+		t.Errorf("Expected the name of the user policy but got %v", p.Name())
+	}
+}
+
+func TestLookupKey(t *testing.T) {
+	k := NewLookupKey([]byte("foo"), 42)
+
+	expectedInternalKey := AppendInternalKey(nil, []byte("foo"), 42, ValueTypeForSeek)
+	if !bytes.Equal(k.InternalKey(), expectedInternalKey) {
+		t.Errorf("Expected %q but got %q", expectedInternalKey, k.InternalKey())
+	}
+	if string(k.UserKey()) != "foo" {
+		t.Errorf("Expected 'foo' but got '%s'", k.UserKey())
+	}
+	if expected := append([]byte{11}, expectedInternalKey...); !bytes.Equal(k.MemtableKey(), expected) {
+		t.Errorf("Expected %q but got %q", expected, k.MemtableKey())
+	}
+}
@@ -0,0 +1,46 @@
+// Package env abstracts the file system that logs and tables are stored in, like the Env of LevelDB.
+//
+// OS is the file system of the operating system. MemFS keeps its files in memory, so that code
+// written against FS can be tested without touching the disk.
+package env
+
+import (
+	"errors"
+	"io"
+)
+
+// File is a file opened through an FS.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.Seeker
+	io.Closer
+	// Sync commits the contents of the file to stable storage.
+	Sync() error
+}
+
+// FS is a file system. Names are slash or OS separated paths, as accepted by path/filepath.
+type FS interface {
+	// Create creates the file name for writing. It fails when name already exists.
+	Create(name string) (File, error)
+	// Open opens the file name for reading.
+	Open(name string) (File, error)
+	// Rename renames the file oldname to newname, replacing newname if it exists.
+	Rename(oldname, newname string) error
+	// Remove removes the file name.
+	Remove(name string) error
+	// List returns the names of the files and directories in dir, in increasing order.
+	List(dir string) ([]string, error)
+	// MkdirAll creates dir along with any parents that do not exist yet.
+	MkdirAll(dir string) error
+	// Lock creates the file name if it does not exist and locks it, so that only one process at a time
+	// uses what it guards. Closing the returned Closer releases the lock.
+	Lock(name string) (io.Closer, error)
+	// SyncDir commits the names of the files in dir to stable storage, so that created, renamed and
+	// removed files survive a crash.
+	SyncDir(dir string) error
+}
+
+// ErrLocked is returned by FS.Lock when the file is locked already.
+var ErrLocked = errors.New("env: file is locked")
@@ -0,0 +1,114 @@
+package env
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testFS checks the behaviour every FS shares in the existing, empty directory dir.
+func testFS(t *testing.T, fs FS, dir string) {
+	name := filepath.Join(dir, "000001.log")
+
+	t.Run("Create and Open", func(t *testing.T) {
+		f, err := fs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("hello world")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Seek(6, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("there"))
+		if err := f.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		if _, err := fs.Create(name); !os.IsExist(err) {
+			t.Errorf("Expected the file to exist but got '%v'", err)
+		}
+
+		r, err := fs.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		if data, _ := ioutil.ReadAll(r); !bytes.Equal(data, []byte("hello there")) {
+			t.Errorf("Expected 'hello there' but got '%s'", data)
+		}
+		if _, err := r.Write([]byte("!")); err == nil {
+			t.Error("Expected writing to a file open for reading to fail")
+		}
+
+		buf := make([]byte, 5)
+		if n, err := r.ReadAt(buf, 6); n != 5 || err != nil && err != io.EOF || string(buf) != "there" {
+			t.Errorf("Expected (5, 'there') but got (%v, '%s'), '%v'", n, buf, err)
+		}
+		if n, err := r.ReadAt(buf, 8); n != 3 || err != io.EOF {
+			t.Errorf("Expected (3, '%v') reading past the end but got (%v, '%v')", io.EOF, n, err)
+		}
+		if _, err := r.ReadAt(buf, 100); err != io.EOF {
+			t.Errorf("Expected '%v' reading after the end but got '%v'", io.EOF, err)
+		}
+	})
+
+	t.Run("MkdirAll and List", func(t *testing.T) {
+		if err := fs.MkdirAll(filepath.Join(dir, "archive", "old")); err != nil {
+			t.Fatal(err)
+		}
+		names, err := fs.List(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := []string{"000001.log", "archive"}; !reflect.DeepEqual(names, expected) {
+			t.Errorf("Expected %v but got %v", expected, names)
+		}
+		if _, err := fs.List(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+			t.Errorf("Expected the directory not to exist but got '%v'", err)
+		}
+		if err := fs.SyncDir(dir); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Rename and Remove", func(t *testing.T) {
+		archived := filepath.Join(dir, "archive", "000001.log")
+		if err := fs.Rename(name, archived); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fs.Open(name); !os.IsNotExist(err) {
+			t.Errorf("Expected the file not to exist but got '%v'", err)
+		}
+		if err := fs.Remove(archived); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Remove(archived); !os.IsNotExist(err) {
+			t.Errorf("Expected the file not to exist but got '%v'", err)
+		}
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		lock, err := fs.Lock(filepath.Join(dir, "LOCK"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fs.Lock(filepath.Join(dir, "LOCK")); err != ErrLocked {
+			t.Errorf("Expected '%v' but got '%v'", ErrLocked, err)
+		}
+		if err := lock.Close(); err != nil {
+			t.Fatal(err)
+		}
+		lock, err = fs.Lock(filepath.Join(dir, "LOCK"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lock.Close()
+	})
+}
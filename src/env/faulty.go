@@ -0,0 +1,282 @@
+package env
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"sync"
+)
+
+// ErrInjected is the error of a write or sync that a FaultyFS was told to fail.
+var ErrInjected = errors.New("env: injected fault")
+
+// ErrCrashed is returned by the files that were open when a FaultyFS crashed.
+var ErrCrashed = errors.New("env: file system crashed")
+
+// FaultyFS wraps an FS to inject the faults that logs have to survive: writes and syncs that fail, data
+// that is lost because it was not synced before a crash, and bits that flip.
+//
+// It keeps track of the files created through it, which are assumed to be written sequentially, as logs
+// are. The data of such a file is durable once the file is synced, and the file itself once its
+// directory is synced. Renames and removes are durable right away. It is safe to use from multiple
+// goroutines.
+type FaultyFS struct {
+	fs FS
+
+	mu    sync.Mutex
+	rand  *rand.Rand
+	files map[string]*faultyState
+	// crashes counts the crashes, so that files opened before the last one can fail.
+	crashes int
+
+	writes, failWrite int
+	syncs, failSync   int
+}
+
+// faultyState is what a FaultyFS knows about a file created through it.
+type faultyState struct {
+	size      int64
+	synced    int64
+	dirSynced bool
+}
+
+// NewFaultyFS wraps fs. The random bit flips and torn crashes are taken from a source seeded with seed.
+func NewFaultyFS(fs FS, seed int64) *FaultyFS {
+	return &FaultyFS{fs: fs, rand: rand.New(rand.NewSource(seed)), files: map[string]*faultyState{}}
+}
+
+// FailWrite makes the nth write from now on fail with ErrInjected, without writing anything.
+func (fs *FaultyFS) FailWrite(n int) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.failWrite = fs.writes + n
+}
+
+// FailSync makes the nth sync of a file from now on fail with ErrInjected.
+func (fs *FaultyFS) FailSync(n int) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.failSync = fs.syncs + n
+}
+
+// Syncs returns the number of syncs of files so far, including the ones that failed.
+func (fs *FaultyFS) Syncs() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.syncs
+}
+
+// Crash simulates a crash that loses everything that was not synced: the files whose directory was not
+// synced since they were created are removed, and the others are cut off after the data that was last
+// synced. Files that were open fail with ErrCrashed afterwards.
+func (fs *FaultyFS) Crash() error {
+	return fs.crash(false)
+}
+
+// TornCrash is a Crash after which a random part of the unsynced data of each file survives, as when
+// the crash happens while the operating system writes the data out.
+func (fs *FaultyFS) TornCrash() error {
+	return fs.crash(true)
+}
+
+func (fs *FaultyFS) crash(torn bool) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.crashes++
+
+	for name, s := range fs.files {
+		if !s.dirSynced {
+			delete(fs.files, name)
+			if err := fs.fs.Remove(name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		size := s.synced
+		if torn && s.size > s.synced {
+			size += fs.rand.Int63n(s.size - s.synced + 1)
+		}
+		if size < s.size {
+			err := fs.rewrite(name, func(data []byte) []byte { return data[:size] })
+			if err != nil {
+				return err
+			}
+		}
+		s.size, s.synced = size, size
+	}
+	return nil
+}
+
+// FlipBits flips n random bits of the file name.
+func (fs *FaultyFS) FlipBits(name string, n int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.rewrite(filepath.Clean(name), func(data []byte) []byte {
+		for i := 0; i < n && len(data) > 0; i++ {
+			data[fs.rand.Intn(len(data))] ^= 1 << uint(fs.rand.Intn(8))
+		}
+		return data
+	})
+}
+
+// rewrite replaces the contents of the file name with what change makes of them.
+func (fs *FaultyFS) rewrite(name string, change func([]byte) []byte) error {
+	f, err := fs.fs.Open(name)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := fs.fs.Remove(name); err != nil {
+		return err
+	}
+	if f, err = fs.fs.Create(name); err != nil {
+		return err
+	}
+	if _, err := f.Write(change(data)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (fs *FaultyFS) Create(name string) (File, error) {
+	f, err := fs.fs.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	name = filepath.Clean(name)
+	fs.files[name] = &faultyState{}
+	return &faultyFile{fs: fs, f: f, name: name, crashes: fs.crashes}, nil
+}
+
+func (fs *FaultyFS) Open(name string) (File, error) {
+	return fs.fs.Open(name)
+}
+
+func (fs *FaultyFS) Rename(oldname, newname string) error {
+	if err := fs.fs.Rename(oldname, newname); err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	oldname, newname = filepath.Clean(oldname), filepath.Clean(newname)
+	delete(fs.files, newname)
+	if s := fs.files[oldname]; s != nil {
+		delete(fs.files, oldname)
+		fs.files[newname] = s
+	}
+	return nil
+}
+
+func (fs *FaultyFS) Remove(name string) error {
+	if err := fs.fs.Remove(name); err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.files, filepath.Clean(name))
+	return nil
+}
+
+func (fs *FaultyFS) List(dir string) ([]string, error) {
+	return fs.fs.List(dir)
+}
+
+func (fs *FaultyFS) MkdirAll(dir string) error {
+	return fs.fs.MkdirAll(dir)
+}
+
+func (fs *FaultyFS) Lock(name string) (io.Closer, error) {
+	return fs.fs.Lock(name)
+}
+
+func (fs *FaultyFS) SyncDir(dir string) error {
+	if err := fs.fs.SyncDir(dir); err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	dir = filepath.Clean(dir)
+	for name, s := range fs.files {
+		if filepath.Dir(name) == dir {
+			s.dirSynced = true
+		}
+	}
+	return nil
+}
+
+// faultyFile is a file created through a FaultyFS.
+type faultyFile struct {
+	fs      *FaultyFS
+	f       File
+	name    string
+	crashes int
+}
+
+func (f *faultyFile) Read(p []byte) (int, error) {
+	return f.f.Read(p)
+}
+
+func (f *faultyFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.f.ReadAt(p, off)
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.crashes != f.fs.crashes {
+		return 0, ErrCrashed
+	}
+	if f.fs.writes++; f.fs.writes == f.fs.failWrite {
+		return 0, ErrInjected
+	}
+
+	n, err := f.f.Write(p)
+	if s := f.fs.files[f.name]; s != nil {
+		if pos, err := f.f.Seek(0, io.SeekCurrent); err == nil && pos > s.size {
+			s.size = pos
+		}
+	}
+	return n, err
+}
+
+func (f *faultyFile) Seek(offset int64, whence int) (int64, error) {
+	return f.f.Seek(offset, whence)
+}
+
+func (f *faultyFile) Sync() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.crashes != f.fs.crashes {
+		return ErrCrashed
+	}
+	if f.fs.syncs++; f.fs.syncs == f.fs.failSync {
+		return ErrInjected
+	}
+
+	if err := f.f.Sync(); err != nil {
+		return err
+	}
+	if s := f.fs.files[f.name]; s != nil {
+		s.synced = s.size
+	}
+	return nil
+}
+
+func (f *faultyFile) Close() error {
+	return f.f.Close()
+}
@@ -0,0 +1,120 @@
+package env
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func readFile(t *testing.T, fs FS, name string) []byte {
+	f, err := fs.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newFaultyFSWithFile(t *testing.T, syncDir bool) (*FaultyFS, File) {
+	fs := NewFaultyFS(NewMemFS(), 1)
+	f, err := fs.Create("000001.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if syncDir {
+		fs.SyncDir(".")
+	}
+	f.Write([]byte("synced"))
+	f.Sync()
+	f.Write([]byte(" unsynced"))
+	return fs, f
+}
+
+func TestFaultyFS_Crash_ShouldDropUnsyncedData(t *testing.T) {
+	fs, f := newFaultyFSWithFile(t, true)
+	if err := fs.Crash(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data := readFile(t, fs, "000001.log"); string(data) != "synced" {
+		t.Errorf("Expected 'synced' but got '%s'", data)
+	}
+	if _, err := f.Write([]byte("more")); err != ErrCrashed {
+		t.Errorf("Expected '%v' but got '%v'", ErrCrashed, err)
+	}
+}
+
+func TestFaultyFS_Crash_ShouldRemoveFilesOfAnUnsyncedDirectory(t *testing.T) {
+	fs, _ := newFaultyFSWithFile(t, false)
+	if err := fs.Crash(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.Open("000001.log"); !os.IsNotExist(err) {
+		t.Errorf("Expected the file not to exist but got '%v'", err)
+	}
+}
+
+func TestFaultyFS_TornCrash_ShouldKeepAPrefixOfTheUnsyncedData(t *testing.T) {
+	for seed := int64(0); seed < 10; seed++ {
+		fs, _ := newFaultyFSWithFile(t, true)
+		fs.rand.Seed(seed)
+		if err := fs.TornCrash(); err != nil {
+			t.Fatal(err)
+		}
+
+		data := readFile(t, fs, "000001.log")
+		if !bytes.HasPrefix([]byte("synced unsynced"), data) || len(data) < len("synced") {
+			t.Errorf("Expected a prefix of 'synced unsynced' but got '%s'", data)
+		}
+	}
+}
+
+func TestFaultyFS_FailWriteAndSync(t *testing.T) {
+	fs := NewFaultyFS(NewMemFS(), 1)
+	f, _ := fs.Create("000001.log")
+	fs.FailWrite(2)
+	fs.FailSync(1)
+
+	if _, err := f.Write([]byte("a")); err != nil {
+		t.Errorf("Expected the first write to succeed but got '%v'", err)
+	}
+	if _, err := f.Write([]byte("b")); err != ErrInjected {
+		t.Errorf("Expected '%v' but got '%v'", ErrInjected, err)
+	}
+	if err := f.Sync(); err != ErrInjected {
+		t.Errorf("Expected '%v' but got '%v'", ErrInjected, err)
+	}
+	if err := f.Sync(); err != nil {
+		t.Errorf("Expected the second sync to succeed but got '%v'", err)
+	}
+	if fs.Syncs() != 2 {
+		t.Errorf("Expected 2 syncs but got %v", fs.Syncs())
+	}
+	if data := readFile(t, fs, "000001.log"); string(data) != "a" {
+		t.Errorf("Expected 'a' but got '%s'", data)
+	}
+}
+
+func TestFaultyFS_FlipBits(t *testing.T) {
+	fs, _ := newFaultyFSWithFile(t, true)
+	if err := fs.FlipBits("000001.log", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	data := readFile(t, fs, "000001.log")
+	var flipped int
+	for i, b := range []byte("synced unsynced") {
+		for x := b ^ data[i]; x != 0; x &= x - 1 {
+			flipped++
+		}
+	}
+	if flipped != 1 {
+		t.Errorf("Expected 1 flipped bit but got %v", flipped)
+	}
+}
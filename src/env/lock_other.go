@@ -0,0 +1,31 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package env
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	lockedMu sync.Mutex
+	locked   = map[string]bool{}
+)
+
+// lockFile is where advisory locks are not available. It only keeps f from being locked twice by this
+// process.
+func lockFile(f *os.File) error {
+	lockedMu.Lock()
+	defer lockedMu.Unlock()
+	if locked[f.Name()] {
+		return ErrLocked
+	}
+	locked[f.Name()] = true
+	return nil
+}
+
+func unlockFile(f *os.File) {
+	lockedMu.Lock()
+	defer lockedMu.Unlock()
+	delete(locked, f.Name())
+}
@@ -0,0 +1,20 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package env
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive advisory lock on f, which is released when f is closed.
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return ErrLocked
+	}
+	return err
+}
+
+// unlockFile does nothing, as closing f releases the lock.
+func unlockFile(f *os.File) {}
@@ -0,0 +1,273 @@
+package env
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// MemFS is an FS that keeps its files in memory. Everything written to it is durable right away, so
+// Sync and SyncDir only check their arguments. It is safe to use from multiple goroutines.
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string]*memNode
+	dirs  map[string]bool
+	locks map[string]bool
+}
+
+// memNode is the contents of a file. Open files keep it after the file is removed or replaced.
+type memNode struct {
+	data []byte
+}
+
+var errorReadOnly = errors.New("env: file is open for reading only")
+var errorClosed = errors.New("env: file is closed")
+
+// NewMemFS creates an empty MemFS. Its root directories, "." and "/", always exist.
+func NewMemFS() *MemFS {
+	return &MemFS{
+		files: map[string]*memNode{},
+		dirs:  map[string]bool{".": true, "/": true},
+		locks: map[string]bool{},
+	}
+}
+
+func pathError(op, name string, err error) error {
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
+
+// checkParent returns an error when the directory of name does not exist.
+func (fs *MemFS) checkParent(op, name string) error {
+	if !fs.dirs[filepath.Dir(name)] {
+		return pathError(op, name, os.ErrNotExist)
+	}
+	return nil
+}
+
+func (fs *MemFS) Create(name string) (File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	name = filepath.Clean(name)
+	if err := fs.checkParent("create", name); err != nil {
+		return nil, err
+	}
+	if fs.files[name] != nil || fs.dirs[name] {
+		return nil, pathError("create", name, os.ErrExist)
+	}
+	n := &memNode{}
+	fs.files[name] = n
+	return &memFile{fs: fs, node: n}, nil
+}
+
+func (fs *MemFS) Open(name string) (File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	n := fs.files[filepath.Clean(name)]
+	if n == nil {
+		return nil, pathError("open", name, os.ErrNotExist)
+	}
+	return &memFile{fs: fs, node: n, readOnly: true}, nil
+}
+
+func (fs *MemFS) Rename(oldname, newname string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	oldname, newname = filepath.Clean(oldname), filepath.Clean(newname)
+	n := fs.files[oldname]
+	if n == nil {
+		return pathError("rename", oldname, os.ErrNotExist)
+	}
+	if err := fs.checkParent("rename", newname); err != nil {
+		return err
+	}
+	delete(fs.files, oldname)
+	fs.files[newname] = n
+	return nil
+}
+
+func (fs *MemFS) Remove(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	name = filepath.Clean(name)
+	if fs.files[name] == nil {
+		return pathError("remove", name, os.ErrNotExist)
+	}
+	delete(fs.files, name)
+	return nil
+}
+
+func (fs *MemFS) List(dir string) ([]string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	dir = filepath.Clean(dir)
+	if !fs.dirs[dir] {
+		return nil, pathError("list", dir, os.ErrNotExist)
+	}
+	var names []string
+	for name := range fs.files {
+		if filepath.Dir(name) == dir {
+			names = append(names, filepath.Base(name))
+		}
+	}
+	for name := range fs.dirs {
+		if name != dir && filepath.Dir(name) == dir {
+			names = append(names, filepath.Base(name))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (fs *MemFS) MkdirAll(dir string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for dir = filepath.Clean(dir); !fs.dirs[dir]; dir = filepath.Dir(dir) {
+		if fs.files[dir] != nil {
+			return pathError("mkdir", dir, os.ErrExist)
+		}
+		fs.dirs[dir] = true
+	}
+	return nil
+}
+
+func (fs *MemFS) Lock(name string) (io.Closer, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	name = filepath.Clean(name)
+	if err := fs.checkParent("lock", name); err != nil {
+		return nil, err
+	}
+	if fs.locks[name] {
+		return nil, ErrLocked
+	}
+	if fs.files[name] == nil {
+		fs.files[name] = &memNode{}
+	}
+	fs.locks[name] = true
+	return &memLock{fs: fs, name: name}, nil
+}
+
+func (fs *MemFS) SyncDir(dir string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if !fs.dirs[filepath.Clean(dir)] {
+		return pathError("sync", dir, os.ErrNotExist)
+	}
+	return nil
+}
+
+type memLock struct {
+	fs       *MemFS
+	name     string
+	released bool
+}
+
+func (l *memLock) Close() error {
+	l.fs.mu.Lock()
+	defer l.fs.mu.Unlock()
+	if l.released {
+		return errorClosed
+	}
+	l.released = true
+	delete(l.fs.locks, l.name)
+	return nil
+}
+
+// memFile is an open MemFS file.
+type memFile struct {
+	fs       *MemFS
+	node     *memNode
+	pos      int64
+	readOnly bool
+	closed   bool
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return 0, errorClosed
+	}
+	if f.pos >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.pos:])
+	f.pos += int64(n)
+	return n, nil
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	switch {
+	case f.closed:
+		return 0, errorClosed
+	case off < 0:
+		return 0, errors.New("env: negative offset")
+	case off >= int64(len(f.node.data)):
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	switch {
+	case f.closed:
+		return 0, errorClosed
+	case f.readOnly:
+		return 0, errorReadOnly
+	}
+	if end := f.pos + int64(len(p)); end > int64(len(f.node.data)) {
+		f.node.data = append(f.node.data, make([]byte, end-int64(len(f.node.data)))...)
+	}
+	copy(f.node.data[f.pos:], p)
+	f.pos += int64(len(p))
+	return len(p), nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return 0, errorClosed
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	}
+	if offset < 0 {
+		return 0, errors.New("env: negative position")
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+func (f *memFile) Sync() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return errorClosed
+	}
+	return nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return errorClosed
+	}
+	f.closed = true
+	return nil
+}
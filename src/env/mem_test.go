@@ -0,0 +1,33 @@
+package env
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMemFS(t *testing.T) {
+	fs := NewMemFS()
+	if err := fs.MkdirAll("db"); err != nil {
+		t.Fatal(err)
+	}
+	testFS(t, fs, "db")
+}
+
+func TestMemFS_Create_ShouldFailWithoutTheDirectory(t *testing.T) {
+	if _, err := NewMemFS().Create("db/000001.log"); !os.IsNotExist(err) {
+		t.Errorf("Expected the directory not to exist but got '%v'", err)
+	}
+}
+
+func TestMemFS_OpenFile_ShouldKeepItsContentsAfterRemove(t *testing.T) {
+	fs := NewMemFS()
+	f, _ := fs.Create("000001.log")
+	f.Write([]byte("hello"))
+	r, _ := fs.Open("000001.log")
+	fs.Remove("000001.log")
+
+	if data, _ := ioutil.ReadAll(r); string(data) != "hello" {
+		t.Errorf("Expected 'hello' but got '%s'", data)
+	}
+}
@@ -0,0 +1,18 @@
+// +build integration
+
+package env
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestOS(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testFS(t, OS, dir)
+}
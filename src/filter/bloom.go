@@ -0,0 +1,110 @@
+package filter
+
+import "encoding/binary"
+
+// bloomPolicy is LevelDB's bloom filter. A filter is a bit array followed by a byte holding the number
+// of probes, k. Each key sets k bits, picked by double hashing from its hash.
+type bloomPolicy struct {
+	bitsPerKey int
+	k          int
+}
+
+// NewBloomFilterPolicy returns a bloom filter policy that uses about bitsPerKey bits per key, which is
+// compatible with LevelDB's NewBloomFilterPolicy. 10 bits per key give about 1% false positives.
+func NewBloomFilterPolicy(bitsPerKey int) FilterPolicy {
+	// k = ln(2) * bits per key minimizes the false positive rate. It is rounded down, as fewer probes
+	// are cheaper.
+	k := int(float64(bitsPerKey) * 0.69)
+	if k < 1 {
+		k = 1
+	}
+	if k > 30 {
+		k = 30
+	}
+	return bloomPolicy{bitsPerKey: bitsPerKey, k: k}
+}
+
+func (p bloomPolicy) Name() string {
+	return "leveldb.BuiltinBloomFilter2"
+}
+
+func (p bloomPolicy) AppendFilter(dst []byte, keys [][]byte) []byte {
+	// Small sets would see a very high false positive rate, so filters have at least 64 bits.
+	bits := len(keys) * p.bitsPerKey
+	if bits < 64 {
+		bits = 64
+	}
+	n := (bits + 7) / 8
+	bits = n * 8
+
+	start := len(dst)
+	dst = append(dst, make([]byte, n)...)
+	dst = append(dst, byte(p.k))
+	array := dst[start : start+n]
+	for _, key := range keys {
+		h := bloomHash(key)
+		delta := h>>17 | h<<15
+		for j := 0; j < p.k; j++ {
+			bit := h % uint32(bits)
+			array[bit/8] |= 1 << (bit % 8)
+			h += delta
+		}
+	}
+	return dst
+}
+
+func (p bloomPolicy) KeyMayMatch(key, filter []byte) bool {
+	if len(filter) < 2 {
+		return false
+	}
+	array := filter[:len(filter)-1]
+	bits := uint32(len(array) * 8)
+
+	k := int(filter[len(filter)-1])
+	if k > 30 {
+		// Reserved for encodings of short bloom filters to come, which count as a match.
+		return true
+	}
+
+	h := bloomHash(key)
+	delta := h>>17 | h<<15
+	for j := 0; j < k; j++ {
+		bit := h % bits
+		if array[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+		h += delta
+	}
+	return true
+}
+
+func bloomHash(key []byte) uint32 {
+	return hash(key, 0xbc9f1d34)
+}
+
+// hash is LevelDB's hash function, which is similar to murmur hash.
+func hash(data []byte, seed uint32) uint32 {
+	const m = 0xc6a4a793
+	const r = 24
+	h := seed ^ uint32(len(data))*m
+
+	for ; len(data) >= 4; data = data[4:] {
+		h += binary.LittleEndian.Uint32(data)
+		h *= m
+		h ^= h >> 16
+	}
+
+	switch len(data) {
+	case 3:
+		h += uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h += uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h += uint32(data[0])
+		h *= m
+		h ^= h >> r
+	}
+	return h
+}
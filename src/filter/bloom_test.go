@@ -0,0 +1,157 @@
+package filter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+func TestHash_KnownValues(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected uint32
+	}{
+		"Empty":       {[]byte{}, 0xbc9f1d34},
+		"One byte":    {[]byte{0x62}, 0xef1345c4},
+		"Two bytes":   {[]byte{0xc3, 0x97}, 0x5b663814},
+		"Three bytes": {[]byte{0xe2, 0x99, 0xa5}, 0x323c078f},
+		"Four bytes":  {[]byte{0xe1, 0x80, 0xb9, 0x32}, 0xed21633a},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if actual := hash(test.input, 0xbc9f1d34); actual != test.expected {
+				t.Errorf("Expected %#x but got %#x", test.expected, actual)
+			}
+		})
+	}
+}
+
+func intKey(i int) []byte {
+	key := make([]byte, 4)
+	binary.LittleEndian.PutUint32(key, uint32(i))
+	return key
+}
+
+func TestBloomFilterPolicy_EmptyFilter(t *testing.T) {
+	p := NewBloomFilterPolicy(10)
+	filter := p.AppendFilter(nil, nil)
+	for _, key := range []string{"hello", "world"} {
+		if p.KeyMayMatch([]byte(key), filter) {
+			t.Errorf("Expected %v not to match an empty filter but it did", key)
+		}
+	}
+}
+
+func TestBloomFilterPolicy_SmallFilter(t *testing.T) {
+	p := NewBloomFilterPolicy(10)
+	filter := p.AppendFilter(nil, [][]byte{[]byte("hello"), []byte("world")})
+
+	for key, expected := range map[string]bool{"hello": true, "world": true, "x": false, "foo": false} {
+		if actual := p.KeyMayMatch([]byte(key), filter); actual != expected {
+			t.Errorf("Expected KeyMayMatch(%v) to be %v but got %v", key, expected, actual)
+		}
+	}
+}
+
+func TestBloomFilterPolicy_ShouldUseLevelDBLayout(t *testing.T) {
+	// Two keys at 10 bits per key get the minimum of 64 bits, followed by the number of probes, which
+	// is 10 * ln(2) rounded down.
+	filter := NewBloomFilterPolicy(10).AppendFilter(nil, [][]byte{[]byte("hello"), []byte("world")})
+	if len(filter) != 9 || filter[8] != 6 {
+		t.Errorf("Expected 8 bytes followed by 6 but got %v", filter)
+	}
+}
+
+func TestBloomFilterPolicy_ShouldMatchLevelDB(t *testing.T) {
+	var twentyKeys []string
+	for i := 0; i < 20; i++ {
+		twentyKeys = append(twentyKeys, fmt.Sprintf("key%d", i))
+	}
+	// The filters that CreateFilter of LevelDB's NewBloomFilterPolicy(10) builds for the keys.
+	tests := map[string]struct {
+		keys     []string
+		expected string
+	}{
+		"Two keys":    {[]string{"hello", "world"}, "\x11\x40\x00\x41\x44\x10\x40\x10\x06"},
+		"Twenty keys": {twentyKeys, "\x3a\x16\x78\x5c\x04\x77\xc4\x34\x68\x0a\x18\xe4\x00\x40\x39\x3b\xe1\x20\x25\xc8\x13\x23\x42\x47\xe4\x06"},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			p := NewBloomFilterPolicy(10)
+			var keys [][]byte
+			for _, key := range test.keys {
+				keys = append(keys, []byte(key))
+			}
+			if actual := p.AppendFilter(nil, keys); string(actual) != test.expected {
+				t.Errorf("Expected %x but got %x", test.expected, actual)
+			}
+			for _, key := range keys {
+				if !p.KeyMayMatch(key, []byte(test.expected)) {
+					t.Errorf("Expected %s to match the filter of LevelDB but it did not", key)
+				}
+			}
+		})
+	}
+}
+
+func TestBloomFilterPolicy_VaryingLengths(t *testing.T) {
+	p := NewBloomFilterPolicy(10)
+	var mediocreFilters, goodFilters int
+
+	for n := 1; n <= 10000; n = nextLength(n) {
+		var keys [][]byte
+		for i := 0; i < n; i++ {
+			keys = append(keys, intKey(i))
+		}
+		filter := p.AppendFilter([]byte("prefix"), keys)[len("prefix"):]
+		if maxLength := n*10/8 + 40; len(filter) > maxLength {
+			t.Errorf("Expected a filter of at most %v bytes for %v keys but got %v", maxLength, n, len(filter))
+		}
+
+		for _, key := range keys {
+			if !p.KeyMayMatch(key, filter) {
+				t.Fatalf("Expected key %v of %v to match but it did not", key, n)
+			}
+		}
+
+		falsePositives := 0
+		for i := 0; i < 10000; i++ {
+			if p.KeyMayMatch(intKey(i+1000000000), filter) {
+				falsePositives++
+			}
+		}
+		rate := float64(falsePositives) / 10000
+		if rate > 0.02 {
+			t.Errorf("Expected a false positive rate of at most 2%% for %v keys but got %v", n, rate)
+		}
+		if rate > 0.0125 {
+			mediocreFilters++
+		} else {
+			goodFilters++
+		}
+	}
+	if mediocreFilters > goodFilters/5 {
+		t.Errorf("Expected mostly good filters but got %v mediocre and %v good", mediocreFilters, goodFilters)
+	}
+}
+
+func TestBloomFilterPolicy_ShouldMatchUnknownEncodings(t *testing.T) {
+	if !NewBloomFilterPolicy(10).KeyMayMatch([]byte("key"), []byte{0x00, 0x00, 31}) {
+		t.Error("Expected a filter with k > 30 to match but it did not")
+	}
+}
+
+// nextLength steps through the lengths of the sets to filter, more finely for small sets.
+func nextLength(n int) int {
+	switch {
+	case n < 10:
+		return n + 1
+	case n < 100:
+		return n + 10
+	case n < 1000:
+		return n + 100
+	}
+	return n + 1000
+}
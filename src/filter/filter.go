@@ -0,0 +1,20 @@
+// Package filter defines the filters that sorted tables keep for their keys, so that lookups of keys a
+// table does not have can skip reading its data blocks.
+package filter
+
+// FilterPolicy creates small filters from sets of keys and tests keys against them. A filter may match
+// keys that were not in the set, but never fails to match one that was.
+//
+// The filters are stored in tables, so a policy must be able to read the filters of the policies with
+// the same Name, including those of other implementations.
+type FilterPolicy interface {
+	// Name identifies the encoding of the filters. It must change whenever the encoding does.
+	Name() string
+
+	// AppendFilter appends the filter of keys to dst. keys may contain duplicates.
+	AppendFilter(dst []byte, keys [][]byte) []byte
+
+	// KeyMayMatch returns false when key was certainly not in the set of keys that filter was created
+	// from.
+	KeyMayMatch(key, filter []byte) bool
+}
@@ -0,0 +1,136 @@
+// Command ldbdump prints the records of log files, or the fragments they are stored in.
+//
+// Usage:
+//
+//	ldbdump [-format text|hex|json] [-fragments] file...
+//
+// In record mode the files are read like a RecordReader in recovery mode does, and the bytes it skips
+// because of corruption are printed along with the records, as is the incomplete record a log may end
+// within. In fragments mode every fragment is printed
+// with its header, along with the trailer padding of the blocks and the space a RecordReader skips.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"env"
+	"logger"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "ldbdump:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("ldbdump", flag.ContinueOnError)
+	format := flags.String("format", "text", "output `format`: text, hex or json")
+	fragments := flags.Bool("fragments", false, "print the fragments instead of the records")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ldbdump [-format text|hex|json] [-fragments] file...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no log files given")
+	}
+
+	p, err := newPrinter(*format, stdout)
+	if err != nil {
+		return err
+	}
+	for _, name := range flags.Args() {
+		if flags.NArg() > 1 {
+			p.file(name)
+		}
+		if err := dumpFile(name, *fragments, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dumpFile(name string, fragments bool, p *printer) error {
+	f, err := env.OS.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if fragments {
+		return dumpFragments(f, p)
+	}
+	return dumpRecords(f, p)
+}
+
+func dumpFragments(src io.Reader, p *printer) error {
+	return logger.ScanFragments(src, func(f logger.Fragment) error {
+		e := entry{
+			Kind:   f.Kind.String(),
+			Offset: &f.Offset,
+			Block:  &f.Block,
+			Length: f.Length,
+		}
+		switch f.Kind {
+		case logger.RecordFragment:
+			e.Type, e.LogNumber, e.ChecksumOK, e.Data = f.Type, f.LogNumber, &f.ChecksumOK, f.Data
+		case logger.Skipped:
+			e.Reason, e.Data = f.Reason.Error(), f.Data
+		}
+		return p.print(e)
+	})
+}
+
+func dumpRecords(src io.ReadSeeker, p *printer) error {
+	var err error
+	rr := logger.NewRecordReaderWithOptions(src, 0, logger.ReaderOptions{
+		Reporter: skippedBytesPrinter{p, &err},
+		Metadata: func(t logger.MetadataType, record []byte) {
+			if err == nil {
+				err = p.print(entry{Kind: "metadata", Type: fmt.Sprint(t), Length: len(record), Data: record})
+			}
+		},
+	})
+	for err == nil && rr.Next() {
+		offset := rr.Offset()
+		err = p.print(entry{Kind: "record", Offset: &offset, Length: len(rr.Record()), Data: rr.Record()})
+	}
+	if err != nil {
+		return err
+	}
+	if rr.Err() != nil {
+		return rr.Err()
+	}
+	if offset, bytes := rr.Truncated(); bytes > 0 {
+		return p.print(entry{Kind: "truncated", Offset: &offset, Length: bytes})
+	}
+	return nil
+}
+
+// skippedBytesPrinter prints the corruption a RecordReader skips. The first error is kept in err.
+type skippedBytesPrinter struct {
+	p   *printer
+	err *error
+}
+
+func (s skippedBytesPrinter) Corruption(bytes int, reason error) {
+	if *s.err == nil {
+		*s.err = s.p.print(entry{Kind: "skipped", Length: bytes, Reason: reason.Error()})
+	}
+}
+
+func (s skippedBytesPrinter) CorruptionAt(offset int64, bytes int, reason error) {
+	if *s.err == nil {
+		*s.err = s.p.print(entry{Kind: "skipped", Offset: &offset, Length: bytes, Reason: reason.Error()})
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"logger"
+)
+
+type seekableBuffer struct {
+	bytes.Buffer
+}
+
+func (b *seekableBuffer) Seek(offset int64, whence int) (int64, error) {
+	return offset, nil
+}
+
+const secondRecordLength = 2*32*1024 - 12 - 2*7 - 6
+
+// testLog returns a log of two records, the second of which spans two blocks and leaves a trailer
+// at the end of the second block, and a metadata record.
+func testLog(t *testing.T) []byte {
+	buf := new(seekableBuffer)
+	w := logger.NewRecordWriter(buf, 0)
+	for _, record := range [][]byte{[]byte("hello"), bytes.Repeat([]byte("a"), secondRecordLength)} {
+		if _, err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := w.WriteMetadata(3, []byte("trace")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func dump(t *testing.T, log []byte, format string, fragments bool) string {
+	out := new(bytes.Buffer)
+	p, err := newPrinter(format, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fragments {
+		err = dumpFragments(bytes.NewReader(log), p)
+	} else {
+		err = dumpRecords(bytes.NewReader(log), p)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestDumpFragments_Text(t *testing.T) {
+	lines := strings.Split(dump(t, testLog(t), "text", true), "\n")
+
+	expected := []string{
+		`fragment offset=0 block=0 type=FULL length=5 checksum=ok "hello"`,
+		`fragment offset=12 block=0 type=FIRST length=32749 checksum=ok`,
+		`fragment offset=32768 block=1 type=LAST length=32755 checksum=ok "aaaa`,
+		`padding offset=65530 block=1 length=6`,
+		`fragment offset=65536 block=2 type=Metadata(3) length=5 checksum=ok "trace"`,
+	}
+	if len(lines) != len(expected)+1 {
+		t.Fatalf("Expected %v lines but got %v", len(expected), len(lines)-1)
+	}
+	for i, e := range expected {
+		if !strings.HasPrefix(lines[i], e) {
+			t.Errorf("Expected '%v' but got '%v'", e, lines[i])
+		}
+	}
+}
+
+func TestDumpFragments_ShouldShowSkippedBytes(t *testing.T) {
+	log := testLog(t)
+	log[7+2] ^= 1
+
+	out := dump(t, log, "text", true)
+	if expected := `fragment offset=0 block=0 type=FULL length=5 checksum=BAD "hemlo"`; !strings.Contains(out, expected) {
+		t.Errorf("Expected '%v' in\n%v", expected, out)
+	}
+	if expected := `skipped offset=12 block=0 length=32756 reason="failed checksum`; !strings.Contains(out, expected) {
+		t.Errorf("Expected '%v' in\n%v", expected, out)
+	}
+}
+
+func TestDumpRecords(t *testing.T) {
+	log := testLog(t)
+	log[7+2] ^= 1
+
+	tests := map[string]struct {
+		format   string
+		expected []string
+	}{
+		"text": {
+			format: "text",
+			expected: []string{
+				`skipped offset=0 length=32768 reason="failed checksum`,
+				`metadata type=3 length=5 "trace"`,
+			},
+		},
+		"hex": {
+			format: "hex",
+			expected: []string{
+				"metadata type=3 length=5\n00000000  74 72 61 63 65",
+			},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			out := dump(t, log, test.format, false)
+			for _, e := range test.expected {
+				if !strings.Contains(out, e) {
+					t.Errorf("Expected '%v' in\n%v", e, out)
+				}
+			}
+		})
+	}
+}
+
+func TestDumpRecords_Golden(t *testing.T) {
+	tests := map[string]struct {
+		damage   func([]byte) []byte
+		expected string
+	}{
+		"Corruption": {
+			damage: func(log []byte) []byte {
+				log[7+2] ^= 1
+				return log
+			},
+			expected: `skipped offset=0 length=32768 reason="failed checksum for record fragment: 1482144011 != 866938760"
+skipped offset=32768 length=32755 reason="unexpected recordType, uninit => LAST not allowed"
+metadata type=3 length=5 "trace"
+`,
+		},
+		"Truncated tail": {
+			damage: func(log []byte) []byte {
+				return log[:40000]
+			},
+			expected: `record offset=0 length=5 "hello"
+truncated offset=12 length=39981
+`,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if out := dump(t, test.damage(testLog(t)), "text", false); out != test.expected {
+				t.Errorf("Expected\n%v\nbut got\n%v", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestDumpRecords_JSON(t *testing.T) {
+	out := dump(t, testLog(t), "json", false)
+
+	var entries []entry
+	dec := json.NewDecoder(strings.NewReader(out))
+	for dec.More() {
+		var e entry
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries but got %v", len(entries))
+	}
+	if e := entries[0]; e.Kind != "record" || *e.Offset != 0 || string(e.Data) != "hello" {
+		t.Errorf("Expected the first record but got %+v", e)
+	}
+	if e := entries[1]; e.Kind != "record" || *e.Offset != 12 || e.Length != secondRecordLength {
+		t.Errorf("Expected the second record but got %+v", e)
+	}
+}
+
+func TestRun_Error(t *testing.T) {
+	tests := map[string][]string{
+		"No files":       {},
+		"Unknown format": {"-format", "xml", "000001.log"},
+		"Missing file":   {"does-not-exist.log"},
+	}
+
+	for testName, args := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if err := run(args, new(bytes.Buffer)); err == nil {
+				t.Error("Expected an error but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// entry is one line of output. Offset, Block and ChecksumOK are pointers so that JSON output can leave
+// out the ones that are not known.
+type entry struct {
+	Kind       string `json:"kind"`
+	File       string `json:"file,omitempty"`
+	Offset     *int64 `json:"offset,omitempty"`
+	Block      *int64 `json:"block,omitempty"`
+	Type       string `json:"type,omitempty"`
+	LogNumber  uint32 `json:"log_number,omitempty"`
+	Length     int    `json:"length"`
+	ChecksumOK *bool  `json:"checksum_ok,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Data       []byte `json:"data,omitempty"`
+}
+
+// maxTextData is the most bytes of data printed in text format. The hex and JSON formats print all of it.
+const maxTextData = 64
+
+type printer struct {
+	format string
+	w      io.Writer
+	json   *json.Encoder
+	name   string
+}
+
+func newPrinter(format string, w io.Writer) (*printer, error) {
+	switch format {
+	case "text", "hex":
+		return &printer{format: format, w: w}, nil
+	case "json":
+		return &printer{format: format, w: w, json: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// file starts the output of the log file name.
+func (p *printer) file(name string) {
+	p.name = name
+	if p.json == nil {
+		fmt.Fprintf(p.w, "== %s ==\n", name)
+	}
+}
+
+func (p *printer) print(e entry) error {
+	if p.json != nil {
+		e.File = p.name
+		return p.json.Encode(e)
+	}
+
+	fields := []string{e.Kind}
+	if e.Offset != nil {
+		fields = append(fields, fmt.Sprintf("offset=%d", *e.Offset))
+	}
+	if e.Block != nil {
+		fields = append(fields, fmt.Sprintf("block=%d", *e.Block))
+	}
+	if e.Type != "" {
+		fields = append(fields, "type="+e.Type)
+	}
+	if e.LogNumber != 0 {
+		fields = append(fields, fmt.Sprintf("log=%d", e.LogNumber))
+	}
+	fields = append(fields, fmt.Sprintf("length=%d", e.Length))
+	if e.ChecksumOK != nil && *e.ChecksumOK {
+		fields = append(fields, "checksum=ok")
+	} else if e.ChecksumOK != nil {
+		fields = append(fields, "checksum=BAD")
+	}
+	if e.Reason != "" {
+		fields = append(fields, fmt.Sprintf("reason=%q", e.Reason))
+	}
+
+	if p.format == "text" && len(e.Data) > maxTextData {
+		fields = append(fields, fmt.Sprintf("%q...", e.Data[:maxTextData]))
+	} else if p.format == "text" && len(e.Data) > 0 {
+		fields = append(fields, fmt.Sprintf("%q", e.Data))
+	}
+	_, err := fmt.Fprintln(p.w, strings.Join(fields, " "))
+	if p.format == "hex" && err == nil && len(e.Data) > 0 {
+		_, err = io.WriteString(p.w, hex.Dump(e.Data))
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+// Command ldbrepair salvages the intact records of a damaged log file into a new log file.
+//
+// Usage:
+//
+//	ldbrepair [-legacy-checksum] [-skip-unknown-types] [-json] damaged.log repaired.log
+//
+// The damaged log is opened read only and the repaired log must not exist yet, so a repair can always
+// be tried again with other options. What was salvaged and every range of bytes that was dropped are
+// printed as a report.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"env"
+	"logger"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "ldbrepair:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("ldbrepair", flag.ContinueOnError)
+	legacyChecksum := flags.Bool("legacy-checksum", false, "also accept the unmasked checksums of earlier versions")
+	skipUnknownTypes := flags.Bool("skip-unknown-types", false, "skip fragments of unknown record types instead of dropping them as corrupt")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ldbrepair [-legacy-checksum] [-skip-unknown-types] [-json] damaged.log repaired.log")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errors.New("expected the damaged and the repaired log file")
+	}
+
+	opts := logger.RepairOptions{
+		Reader: logger.ReaderOptions{LegacyChecksum: *legacyChecksum, SkipUnknownTypes: *skipUnknownTypes},
+	}
+	report, err := repairFile(flags.Arg(0), flags.Arg(1), opts)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSONReport(stdout, report)
+	}
+	return printReport(stdout, report)
+}
+
+// repairFile repairs the log src into the new file dest, which is removed again when the repair fails.
+func repairFile(src, dest string, opts logger.RepairOptions) (logger.RepairReport, error) {
+	in, err := env.OS.Open(src)
+	if err != nil {
+		return logger.RepairReport{}, err
+	}
+	defer in.Close()
+	out, err := env.OS.Create(dest)
+	if err != nil {
+		return logger.RepairReport{}, err
+	}
+
+	report, err := logger.Repair(in, out, opts)
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		env.OS.Remove(dest)
+	}
+	return report, err
+}
+
+func printReport(w io.Writer, report logger.RepairReport) error {
+	fmt.Fprintf(w, "salvaged %d records (%d bytes) and %d metadata records\n", report.Records, report.RecordBytes, report.Metadata)
+	for _, d := range report.Dropped {
+		fmt.Fprintf(w, "dropped %d bytes at offset %d: %v\n", d.Bytes, d.Offset, d.Reason)
+	}
+	_, err := fmt.Fprintf(w, "dropped %d ranges\n", len(report.Dropped))
+	return err
+}
+
+type jsonDropped struct {
+	Offset int64  `json:"offset"`
+	Bytes  int    `json:"bytes"`
+	Reason string `json:"reason"`
+}
+
+func printJSONReport(w io.Writer, report logger.RepairReport) error {
+	dropped := make([]jsonDropped, 0, len(report.Dropped))
+	for _, d := range report.Dropped {
+		dropped = append(dropped, jsonDropped{d.Offset, d.Bytes, d.Reason.Error()})
+	}
+	return json.NewEncoder(w).Encode(struct {
+		Records     int64         `json:"records"`
+		RecordBytes int64         `json:"record_bytes"`
+		Metadata    int64         `json:"metadata"`
+		Dropped     []jsonDropped `json:"dropped"`
+	}{report.Records, report.RecordBytes, report.Metadata, dropped})
+}
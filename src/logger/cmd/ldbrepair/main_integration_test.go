@@ -0,0 +1,49 @@
+// +build integration
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"logger"
+)
+
+func TestRepairFile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "ldbrepair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	damaged, repaired := filepath.Join(dir, "000001.log"), filepath.Join(dir, "repaired.log")
+
+	f, err := os.Create(damaged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := logger.NewRecordWriter(f, 0)
+	w.Write([]byte("first"))
+	w.Write([]byte("second"))
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	original, _ := ioutil.ReadFile(damaged)
+
+	if err := run([]string{damaged, repaired}, new(bytes.Buffer)); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := ioutil.ReadFile(damaged); !bytes.Equal(after, original) {
+		t.Error("Expected the damaged log to be left as it was")
+	}
+	if after, _ := ioutil.ReadFile(repaired); !bytes.Equal(after, original) {
+		t.Error("Expected the repaired log to have the same records")
+	}
+
+	if err := run([]string{damaged, repaired}, new(bytes.Buffer)); err == nil {
+		t.Error("Expected an error for a repaired log that exists but got nil")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"logger"
+)
+
+var testReport = logger.RepairReport{
+	Records:     2,
+	RecordBytes: 10,
+	Metadata:    1,
+	Dropped: []logger.DroppedBytes{
+		{Offset: 12, Bytes: 100, Reason: errors.New("failed checksum")},
+	},
+}
+
+func TestPrintReport(t *testing.T) {
+	out := new(bytes.Buffer)
+	if err := printReport(out, testReport); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "salvaged 2 records (10 bytes) and 1 metadata records\n" +
+		"dropped 100 bytes at offset 12: failed checksum\n" +
+		"dropped 1 ranges\n"
+	if out.String() != expected {
+		t.Errorf("Expected '%v' but got '%v'", expected, out.String())
+	}
+}
+
+func TestPrintJSONReport(t *testing.T) {
+	out := new(bytes.Buffer)
+	if err := printJSONReport(out, testReport); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"records":2,"record_bytes":10,"metadata":1,"dropped":[{"offset":12,"bytes":100,"reason":"failed checksum"}]}` + "\n"
+	if out.String() != expected {
+		t.Errorf("Expected '%v' but got '%v'", expected, out.String())
+	}
+}
+
+func TestRun_Error(t *testing.T) {
+	tests := map[string][]string{
+		"No files":     {},
+		"One file":     {"000001.log"},
+		"Missing file": {"does-not-exist.log", "repaired.log"},
+	}
+
+	for testName, args := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if err := run(args, new(bytes.Buffer)); err == nil {
+				t.Error("Expected an error but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+
+	"snappy"
+)
+
+// Compression is the algorithm a RecordWriter compresses user records with.
+type Compression uint8
+
+const (
+	NoCompression Compression = iota
+	SnappyCompression
+	FlateCompression
+)
+
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case SnappyCompression:
+		return "snappy"
+	case FlateCompression:
+		return "flate"
+	default:
+		return fmt.Sprintf("Invalid compression %d", int(c))
+	}
+}
+
+// CompressionStats count the bytes of user records before and after compression. UncompressedBytes is
+// the size of the records themselves and CompressedBytes the size of what is stored for them in the log,
+// not counting record headers or encryption. A record that does not get smaller is stored as is and counts the same
+// on both sides.
+type CompressionStats struct {
+	UncompressedBytes int64
+	CompressedBytes   int64
+}
+
+func (s *CompressionStats) add(uncompressed, compressed int) {
+	s.UncompressedBytes += int64(uncompressed)
+	s.CompressedBytes += int64(compressed)
+}
+
+var errorEmptyCompressedRecord = errors.New("logger: compressed record is empty")
+
+// compressor compresses user records for a RecordWriter.
+//
+// A compressed record is stored as the Compression it was compressed with, in one byte, followed by the
+// compressed data.
+type compressor struct {
+	compression Compression
+	buf         []byte
+	flateBuf    bytes.Buffer
+	flate       *flate.Writer
+}
+
+// compress returns what to store for record p and whether it is compressed. The result is only valid
+// until the next call.
+func (c *compressor) compress(p []byte) ([]byte, bool) {
+	var stored []byte
+	switch c.compression {
+	case SnappyCompression:
+		if n := 1 + snappy.MaxEncodedLen(len(p)); cap(c.buf) < n {
+			c.buf = make([]byte, n)
+		}
+		c.buf[0] = byte(SnappyCompression)
+		stored = c.buf[:1+len(snappy.Encode(c.buf[1:cap(c.buf)], p))]
+	case FlateCompression:
+		c.flateBuf.Reset()
+		c.flateBuf.WriteByte(byte(FlateCompression))
+		if c.flate == nil {
+			c.flate, _ = flate.NewWriter(&c.flateBuf, flate.BestSpeed)
+		} else {
+			c.flate.Reset(&c.flateBuf)
+		}
+		c.flate.Write(p)
+		c.flate.Close()
+		stored = c.flateBuf.Bytes()
+	default:
+		return p, false
+	}
+
+	if len(stored) >= len(p) {
+		return p, false
+	}
+	return stored, true
+}
+
+// decompressor decompresses the user records read by a RecordReader.
+type decompressor struct {
+	buf      []byte
+	flateBuf bytes.Buffer
+	flate    io.ReadCloser
+}
+
+// decompress returns the user record stored as the compressed payload p. The result is only valid
+// until the next call.
+func (d *decompressor) decompress(p []byte) ([]byte, error) {
+	if len(p) == 0 {
+		return nil, errorEmptyCompressedRecord
+	}
+
+	switch compression, data := Compression(p[0]), p[1:]; compression {
+	case SnappyCompression:
+		record, err := snappy.Decode(d.buf[:cap(d.buf)], data)
+		if err != nil {
+			return nil, fmt.Errorf("logger: cannot decompress record: %v", err)
+		}
+		d.buf = record
+		return record, nil
+	case FlateCompression:
+		if d.flate == nil {
+			d.flate = flate.NewReader(bytes.NewReader(data))
+		} else {
+			d.flate.(flate.Resetter).Reset(bytes.NewReader(data), nil)
+		}
+		d.flateBuf.Reset()
+		if _, err := d.flateBuf.ReadFrom(d.flate); err != nil {
+			return nil, fmt.Errorf("logger: cannot decompress record: %v", err)
+		}
+		return d.flateBuf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("logger: unknown compression %v", compression)
+	}
+}
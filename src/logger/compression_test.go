@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompression_RoundTrip(t *testing.T) {
+	tests := map[string]struct {
+		compression Compression
+		recyclable  bool
+	}{
+		"None":                       {compression: NoCompression},
+		"Snappy":                     {compression: SnappyCompression},
+		"Flate":                      {compression: FlateCompression},
+		"Snappy in a recyclable log": {compression: SnappyCompression, recyclable: true},
+	}
+
+	inputs := [][]byte{
+		[]byte(`{"key": "a", "value": "b"}`),
+		bytes.Repeat([]byte(`{"key": "user", "value": "0123456789"}`), 5000),
+		repeat(50, 3*blockSize),
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			fs, f := newTestLog(t)
+			w := NewRecordWriterWithOptions(f, 0, WriterOptions{
+				Compression: test.compression,
+				Recyclable:  test.recyclable,
+				LogNumber:   7,
+			})
+			for _, input := range inputs {
+				writeFailOnError(t, w, input)
+			}
+
+			rr := NewRecordReader(openTestLog(t, fs), 0)
+			for _, input := range inputs {
+				readRecordAndVerify(t, rr, input)
+			}
+			verifyEOF(t, rr)
+
+			if rr.CompressionStats() != w.CompressionStats() {
+				t.Errorf("Expected reader stats %+v but got %+v", w.CompressionStats(), rr.CompressionStats())
+			}
+		})
+	}
+}
+
+func TestCompression_Next(t *testing.T) {
+	input := bytes.Repeat([]byte("compressible "), 10000)
+	fs, f := newTestLog(t)
+	w := NewRecordWriterWithOptions(f, 0, WriterOptions{Compression: SnappyCompression})
+	writeFailOnError(t, w, []byte("hello world"))
+	writeFailOnError(t, w, input)
+
+	rr := NewRecordReader(openTestLog(t, fs), 0)
+	for _, expected := range [][]byte{[]byte("hello world"), input} {
+		if !rr.Next() {
+			t.Fatalf("Expected a record but got '%v'", rr.Err())
+		}
+		if !bytes.Equal(rr.Record(), expected) {
+			t.Errorf("Expected a record of %v bytes but got %v bytes", len(expected), len(rr.Record()))
+		}
+	}
+	if rr.Next() || rr.Err() != nil {
+		t.Errorf("Expected the end of the log but got '%v'", rr.Err())
+	}
+}
+
+func TestCompression_Stats(t *testing.T) {
+	_, f := newTestLog(t)
+	w := NewRecordWriterWithOptions(f, 0, WriterOptions{Compression: SnappyCompression})
+	compressible := bytes.Repeat([]byte("a"), 1000)
+	writeFailOnError(t, w, compressible)
+
+	stats := w.CompressionStats()
+	if stats.UncompressedBytes != 1000 {
+		t.Errorf("Expected 1000 uncompressed bytes but got %v", stats.UncompressedBytes)
+	}
+	if stats.CompressedBytes >= 100 {
+		t.Errorf("Expected less than 100 compressed bytes but got %v", stats.CompressedBytes)
+	}
+
+	incompressible := []byte("abc")
+	writeFailOnError(t, w, incompressible)
+	if expected := stats.CompressedBytes + 3; w.CompressionStats().CompressedBytes != expected {
+		t.Errorf("Expected %v compressed bytes but got %v", expected, w.CompressionStats().CompressedBytes)
+	}
+}
+
+func TestCompression_IncompressibleRecord_ShouldBeStoredUncompressed(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriterWithOptions(f, 0, WriterOptions{Compression: FlateCompression})
+	input := []byte("hello world")
+	writeFailOnError(t, w, input)
+
+	verifyRecord(t, readTestLog(t, fs), input, FULL)
+}
+
+func TestCompression_CompressedRecord_ShouldFlagItsFirstFragment(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriterWithOptions(f, 0, WriterOptions{Compression: SnappyCompression})
+	writeFailOnError(t, w, repeat(50, 1000))
+
+	if rt := header(readTestLog(t, fs)).RecordType(); rt != FULL|compressedFlag {
+		t.Errorf("Expected '%v' but got '%v'", FULL|compressedFlag, rt)
+	}
+}
+
+func TestCompression_CompressedTypeWithinAFragmentedRecord_ShouldBeAnError(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	w.writeRecordFragment(FIRST, []byte("hello "))
+	w.writeRecordFragment(LAST|compressedFlag, []byte("world"))
+	w.Flush()
+
+	_, err := NewRecordReader(openTestLog(t, fs), 0).Read(new(bytes.Buffer))
+	if expectedErr := (RecordTypeMissmatchError{FIRST, LAST | compressedFlag}); err != expectedErr {
+		t.Errorf("Expected '%v' but got '%v'", expectedErr, err)
+	}
+}
+
+func TestCompression_CorruptedPayload(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	w.writeRecordFragment(FULL|compressedFlag, []byte{byte(SnappyCompression), 0xff, 0xff, 0xff})
+	writeFailOnError(t, w, []byte("second"))
+
+	t.Run("Should be an error by default", func(t *testing.T) {
+		_, err := NewRecordReader(openTestLog(t, fs), 0).Read(new(bytes.Buffer))
+		if err == nil {
+			t.Error("Expected an error but got nil")
+		}
+	})
+
+	t.Run("Should be reported and skipped in recovery mode", func(t *testing.T) {
+		reporter := new(recordingReporter)
+		rr := NewRecordReaderWithOptions(openTestLog(t, fs), 0, ReaderOptions{Reporter: reporter})
+		readRecordAndVerify(t, rr, []byte("second"))
+		verifyEOF(t, rr)
+		if len(reporter.droppedBytes) != 1 || reporter.droppedBytes[0] != 4 {
+			t.Errorf("Expected a corruption of 4 bytes but got %v", reporter.droppedBytes)
+		}
+	})
+}
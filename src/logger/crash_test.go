@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"env"
+)
+
+const crashTestRuns = 200
+
+// crashTestRecord returns the ith record of a crash test, which is easy to compress and at most three
+// blocks long.
+func crashTestRecord(r *rand.Rand, i int) []byte {
+	record := []byte(fmt.Sprintf("record %v:", i))
+	return append(record, bytes.Repeat([]byte{byte(i)}, r.Intn(3*blockSize))...)
+}
+
+// writeUntilCrash writes random records to the log name on fs, syncing now and then, until an injected
+// fault stops the writer or it has written enough. It then crashes fs and returns the records the writer
+// accepted and how many of them were acknowledged as synced.
+func writeUntilCrash(t *testing.T, r *rand.Rand, fs *env.FaultyFS, name string, opts WriterOptions) ([][]byte, int) {
+	f, err := fs.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.SyncDir("."); err != nil {
+		t.Fatal(err)
+	}
+	if r.Intn(2) == 0 {
+		fs.FailWrite(1 + r.Intn(20))
+	}
+	if r.Intn(2) == 0 {
+		fs.FailSync(1 + r.Intn(10))
+	}
+
+	w := NewRecordWriterWithOptions(f, 0, opts)
+	var written [][]byte
+	var synced int
+	for i := 0; i < 50; i++ {
+		record := crashTestRecord(r, i)
+		sync := r.Intn(5) == 0
+		if _, err := w.WriteWithOptions(record, WriteOptions{Sync: sync}); err != nil {
+			break
+		}
+		written = append(written, record)
+		if !sync && r.Intn(10) == 0 {
+			if err := w.Sync(); err != nil {
+				break
+			}
+			sync = true
+		}
+		if sync {
+			synced = len(written)
+		}
+	}
+
+	if r.Intn(2) == 0 {
+		err = fs.Crash()
+	} else {
+		err = fs.TornCrash()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return written, synced
+}
+
+// Whatever faults are injected and wherever the crash happens, the records recovered from the log should
+// be the first records that were written, including at least every record acknowledged as synced, and
+// no corruption should be found.
+func TestRecordReader_Crash_ShouldRecoverEverySyncedRecord(t *testing.T) {
+	tests := map[string]struct {
+		writer WriterOptions
+		reader ReaderOptions
+	}{
+		"Plain":      {},
+		"Recyclable": {WriterOptions{Recyclable: true, LogNumber: 1}, ReaderOptions{LogNumber: 1}},
+		"Snappy":     {WriterOptions{Compression: SnappyCompression}, ReaderOptions{}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			for seed := int64(0); seed < crashTestRuns; seed++ {
+				r := rand.New(rand.NewSource(seed))
+				fs := env.NewFaultyFS(env.NewMemFS(), seed)
+				written, synced := writeUntilCrash(t, r, fs, "000001.log", test.writer)
+
+				f, err := fs.Open("000001.log")
+				if err != nil {
+					t.Fatal(err)
+				}
+				reporter := &recordingReporter{}
+				opts := test.reader
+				opts.Reporter = reporter
+				rr := NewRecordReaderWithOptions(f, 0, opts)
+				var recovered int
+				for ; rr.Next(); recovered++ {
+					if recovered >= len(written) || !bytes.Equal(rr.Record(), written[recovered]) {
+						t.Fatalf("Seed %v: expected record %v to be one that was written", seed, recovered)
+					}
+				}
+				f.Close()
+
+				if rr.Err() != nil {
+					t.Errorf("Seed %v: expected no error but got '%v'", seed, rr.Err())
+				}
+				if len(reporter.reasons) != 0 {
+					t.Errorf("Seed %v: expected no corruption but got %v", seed, reporter.reasons)
+				}
+				if recovered < synced {
+					t.Errorf("Seed %v: expected at least %v synced records but got %v", seed, synced, recovered)
+				}
+			}
+		})
+	}
+}
+
+// Bits flipped after a crash should only ever cost records, the records recovered should still be
+// records that were written, in the order they were written.
+func TestRecordReader_CrashAndBitFlips_ShouldOnlyRecoverWrittenRecords(t *testing.T) {
+	for seed := int64(0); seed < crashTestRuns; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		fs := env.NewFaultyFS(env.NewMemFS(), seed)
+		written, _ := writeUntilCrash(t, r, fs, "000001.log", WriterOptions{})
+		if err := fs.FlipBits("000001.log", 1+r.Intn(5)); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := fs.Open("000001.log")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := NewRecordReaderWithOptions(f, 0, ReaderOptions{Reporter: &recordingReporter{}})
+		next := 0
+		for rr.Next() {
+			for next < len(written) && !bytes.Equal(rr.Record(), written[next]) {
+				next++
+			}
+			if next == len(written) {
+				t.Fatalf("Seed %v: expected only records that were written, in order", seed)
+			}
+			next++
+		}
+		f.Close()
+
+		if rr.Err() != nil {
+			t.Errorf("Seed %v: expected no error but got '%v'", seed, rr.Err())
+		}
+	}
+}
@@ -23,6 +23,9 @@
 //       type: uint8          // One of FULL, FIRST, MIDDLE, LAST
 //       data: uint8[length]
 //
+// As in LevelDB, the checksum is stored masked (rotated right by 15 bits plus a
+// constant) so that log files can be exchanged with the C++ implementation.
+//
 // A record never starts within the last six bytes of a block (since it won't fit).
 // Any leftover bytes here form the trailer, which must consist entirely of zero
 // bytes and must be skipped by readers.
@@ -62,4 +65,45 @@
 // bytes free in the third block, which will be left empty as the trailer.
 //
 // **C** will be stored as a FULL record in the fourth block.
+//
+// Logs written to reused files use the recyclable variants of these types, as
+// RocksDB does. Their header also carries the number of the log, which is
+// covered by the checksum, so a reader stops at the first record left over
+// from an earlier use of the file:
+//
+//     RecyclableFULL == 5
+//     RecyclableFIRST == 6
+//     RecyclableMIDDLE == 7
+//     RecyclableLAST == 8
+//
+//     recyclable record :=
+//       checksum: uint32     // crc32c of type, log number and data[]
+//       length: uint16
+//       type: uint8
+//       log number: uint32   // little-endian
+//       data: uint8[length]
+//
+// Record types 64 to 127 are reserved for user defined metadata records, such
+// as tracing markers. A metadata record is always a single fragment. Readers
+// hand them to a callback rather than returning them, and readers that skip
+// record types they do not understand skip them too.
+//
+// A compressed user record has the 0x80 bit set in the type of its FULL or
+// FIRST fragment, which readers that do not know about compression reject as
+// an unknown type. Its data, once the fragments are put together, is one byte
+// naming the compression (1 for snappy, 2 for flate) followed by the
+// compressed record. Records that do not get smaller are stored uncompressed.
+//
+// An encrypted log starts with a PREAMBLE record, of type 9, that names the
+// key the log is encrypted with:
+//
+//     preamble :=
+//       version: uint8       // 1
+//       key id: uint32       // little-endian
+//       file id: uint8[16]   // random
+//
+// Every user record of the log, after compression, is then stored as a random
+// 12 byte nonce followed by the record sealed with AES-GCM. The file id and the
+// offset of the record's first fragment, as a little-endian uint64, are
+// authenticated along with it.
 package logger
@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KeyProvider supplies the AES keys that logs are encrypted with. A key is 16, 24 or 32 bytes long and
+// is known by an ID, which is stored in the preamble of every log encrypted with it. Keys are rotated
+// by changing the current key: logs created from then on use the new key while older logs are still
+// read with the key named in their preamble.
+type KeyProvider interface {
+	// CurrentKey returns the key that new logs are encrypted with and its ID.
+	CurrentKey() (id uint32, key []byte, err error)
+	// Key returns the key with the given ID.
+	Key(id uint32) ([]byte, error)
+}
+
+// ErrTampered is returned when an encrypted record fails authentication. Unlike a checksum failure,
+// which a torn write leaves behind, it means that the record was altered or moved after it was written,
+// so recovery mode does not skip over it either.
+var ErrTampered = errors.New("logger: encrypted record failed authentication")
+
+var errorEncryptedLog = errors.New("logger: log is encrypted but no KeyProvider was given")
+var errorMissingPreamble = errors.New("logger: log does not start with an encryption preamble")
+var errorBadPreamble = errors.New("logger: bad encryption preamble")
+var errorEncryptedAppend = errors.New("logger: encrypted logs can only be written from the start")
+var errorEncryptionInRecyclableLog = errors.New("logger: recyclable logs cannot be encrypted")
+
+const (
+	preambleVersion = 1
+	fileIDSize      = 16
+	// preambleSize is the size of the body of the PREAMBLE record: a version byte, the key ID (uint32)
+	// and a random file ID.
+	preambleSize = 1 + 4 + fileIDSize
+)
+
+// recordCipher encrypts and decrypts the user records of one log with AES-GCM.
+//
+// A record is stored as a random nonce followed by the sealed record. The file ID and the offset of the
+// record are authenticated along with it, so a record cannot be moved within the log or to another log
+// that uses the same key.
+type recordCipher struct {
+	aead   cipher.AEAD
+	fileID [fileIDSize]byte
+	ad     [fileIDSize + 8]byte
+	buf    []byte
+}
+
+func newRecordCipher(key []byte, fileID [fileIDSize]byte) (*recordCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("logger: bad encryption key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("logger: bad encryption key: %v", err)
+	}
+	c := &recordCipher{aead: aead, fileID: fileID}
+	copy(c.ad[:], fileID[:])
+	return c, nil
+}
+
+// newWriterCipher creates the cipher for a new log with the current key and returns it along with the
+// body of the PREAMBLE record that identifies it.
+func newWriterCipher(keys KeyProvider) (*recordCipher, []byte, error) {
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return nil, nil, fmt.Errorf("logger: cannot get the current key: %v", err)
+	}
+	var fileID [fileIDSize]byte
+	if _, err := io.ReadFull(rand.Reader, fileID[:]); err != nil {
+		return nil, nil, fmt.Errorf("logger: cannot create file ID: %v", err)
+	}
+	c, err := newRecordCipher(key, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	preamble := make([]byte, preambleSize)
+	preamble[0] = preambleVersion
+	binary.LittleEndian.PutUint32(preamble[1:5], id)
+	copy(preamble[5:], fileID[:])
+	return c, preamble, nil
+}
+
+// newReaderCipher creates the cipher for the log that starts with the PREAMBLE record body preamble.
+func newReaderCipher(keys KeyProvider, preamble []byte) (*recordCipher, error) {
+	if len(preamble) != preambleSize || preamble[0] != preambleVersion {
+		return nil, errorBadPreamble
+	}
+	id := binary.LittleEndian.Uint32(preamble[1:5])
+	key, err := keys.Key(id)
+	if err != nil {
+		return nil, fmt.Errorf("logger: cannot get key %d: %v", id, err)
+	}
+	var fileID [fileIDSize]byte
+	copy(fileID[:], preamble[5:])
+	return newRecordCipher(key, fileID)
+}
+
+// seal encrypts record p that starts at offset. The result is only valid until the next call.
+func (c *recordCipher) seal(p []byte, offset int64) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	if n := nonceSize + len(p) + c.aead.Overhead(); cap(c.buf) < n {
+		c.buf = make([]byte, n)
+	}
+	nonce := c.buf[:nonceSize]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("logger: cannot create nonce: %v", err)
+	}
+	return c.aead.Seal(nonce, nonce, p, c.additionalData(offset)), nil
+}
+
+// open decrypts the record p that starts at offset. The result is only valid until the next call.
+func (c *recordCipher) open(p []byte, offset int64) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	if len(p) < nonceSize+c.aead.Overhead() {
+		return nil, ErrTampered
+	}
+	record, err := c.aead.Open(c.buf[:0], p[:nonceSize], p[nonceSize:], c.additionalData(offset))
+	if err != nil {
+		return nil, ErrTampered
+	}
+	c.buf = record
+	return record, nil
+}
+
+func (c *recordCipher) additionalData(offset int64) []byte {
+	binary.LittleEndian.PutUint64(c.ad[fileIDSize:], uint64(offset))
+	return c.ad[:]
+}
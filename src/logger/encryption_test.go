@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"crc32c"
+)
+
+type testKeys struct {
+	current uint32
+	keys    map[uint32][]byte
+}
+
+func newTestKeys() *testKeys {
+	return &testKeys{
+		current: 1,
+		keys: map[uint32][]byte{
+			1: repeat(1, 16),
+			2: repeat(2, 32),
+		},
+	}
+}
+
+func (k *testKeys) CurrentKey() (uint32, []byte, error) {
+	return k.current, k.keys[k.current], nil
+}
+
+func (k *testKeys) Key(id uint32) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, errors.New("unknown key")
+	}
+	return key, nil
+}
+
+func writeEncryptedLog(t *testing.T, keys KeyProvider, compression Compression, inputs ...[]byte) []byte {
+	fs, f := newTestLog(t)
+	w := NewRecordWriterWithOptions(f, 0, WriterOptions{Keys: keys, Compression: compression})
+	for _, input := range inputs {
+		writeFailOnError(t, w, input)
+	}
+	return readTestLog(t, fs)
+}
+
+func TestEncryption_RoundTrip(t *testing.T) {
+	inputs := [][]byte{
+		[]byte("hello world"),
+		repeat(50, 3*blockSize),
+		bytes.Repeat([]byte(`{"key": "user", "value": "0123456789"}`), 5000),
+		[]byte("last"),
+	}
+
+	for _, compression := range []Compression{NoCompression, SnappyCompression} {
+		t.Run(compression.String(), func(t *testing.T) {
+			log := writeEncryptedLog(t, newTestKeys(), compression, inputs...)
+			if bytes.Contains(log, []byte("hello world")) {
+				t.Error("Expected the records to be encrypted but found one in the log")
+			}
+
+			rr := NewRecordReaderWithOptions(bytes.NewReader(log), 0, ReaderOptions{Keys: newTestKeys()})
+			for _, input := range inputs {
+				readRecordAndVerify(t, rr, input)
+			}
+			verifyEOF(t, rr)
+
+			rr = NewRecordReaderWithOptions(bytes.NewReader(log), 0, ReaderOptions{Keys: newTestKeys()})
+			for _, input := range inputs {
+				if !rr.Next() {
+					t.Fatalf("Expected a record but got '%v'", rr.Err())
+				}
+				if !bytes.Equal(rr.Record(), input) {
+					t.Errorf("Expected a record of %v bytes but got %v bytes", len(input), len(rr.Record()))
+				}
+			}
+		})
+	}
+}
+
+func TestEncryption_KeyRotation_ShouldReadLogsWrittenWithAnEarlierKey(t *testing.T) {
+	keys := newTestKeys()
+	first := writeEncryptedLog(t, keys, NoCompression, []byte("first"))
+	keys.current = 2
+	second := writeEncryptedLog(t, keys, NoCompression, []byte("second"))
+
+	readRecordAndVerify(t, NewRecordReaderWithOptions(bytes.NewReader(first), 0, ReaderOptions{Keys: keys}), []byte("first"))
+	readRecordAndVerify(t, NewRecordReaderWithOptions(bytes.NewReader(second), 0, ReaderOptions{Keys: keys}), []byte("second"))
+
+	delete(keys.keys, 1)
+	_, err := NewRecordReaderWithOptions(bytes.NewReader(first), 0, ReaderOptions{Keys: keys}).Read(new(bytes.Buffer))
+	if err == nil {
+		t.Error("Expected an error for the missing key but got nil")
+	}
+}
+
+func TestEncryption_InitialOffset_ShouldReadThePreamble(t *testing.T) {
+	log := writeEncryptedLog(t, newTestKeys(), NoCompression, repeat(50, blockSize), []byte("second"))
+
+	// Starts within the first record, right after the PREAMBLE record.
+	rr := NewRecordReaderWithOptions(bytes.NewReader(log), recordHeaderSize+preambleSize+1, ReaderOptions{Keys: newTestKeys()})
+	readRecordAndVerify(t, rr, []byte("second"))
+	verifyEOF(t, rr)
+}
+
+func TestEncryption_Tampering(t *testing.T) {
+	const firstRecord = 2*recordHeaderSize + preambleSize
+	tests := map[string]struct {
+		tamper        func(log []byte)
+		expectedError error
+	}{
+		"Changed record": {
+			tamper: func(log []byte) {
+				log[firstRecord+20] ^= 1
+				rechecksum(log[firstRecord-recordHeaderSize:])
+			},
+			expectedError: ErrTampered,
+		},
+		"Swapped records": {
+			tamper: func(log []byte) {
+				const size = recordHeaderSize + 12 + 5 + 16
+				first := append([]byte{}, log[firstRecord-recordHeaderSize:][:size]...)
+				copy(log[firstRecord-recordHeaderSize:], log[firstRecord-recordHeaderSize+size:][:size])
+				copy(log[firstRecord-recordHeaderSize+size:], first)
+			},
+			expectedError: ErrTampered,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			log := writeEncryptedLog(t, newTestKeys(), NoCompression, []byte("first"), []byte("other"))
+			test.tamper(log)
+
+			_, err := NewRecordReaderWithOptions(bytes.NewReader(log), 0, ReaderOptions{Keys: newTestKeys()}).Read(new(bytes.Buffer))
+			if err != test.expectedError {
+				t.Errorf("Expected '%v' but got '%v'", test.expectedError, err)
+			}
+
+			rr := NewRecordReaderWithOptions(bytes.NewReader(log), 0, ReaderOptions{
+				Keys:     newTestKeys(),
+				Reporter: new(recordingReporter),
+			})
+			if rr.Next() || rr.Err() != test.expectedError {
+				t.Errorf("Expected '%v' in recovery mode but got '%v'", test.expectedError, rr.Err())
+			}
+		})
+	}
+}
+
+func TestEncryption_DamagedRecord_ShouldBeAChecksumFailure(t *testing.T) {
+	log := writeEncryptedLog(t, newTestKeys(), NoCompression, []byte("first"))
+	log[len(log)-1] ^= 1
+
+	_, err := NewRecordReaderWithOptions(bytes.NewReader(log), 0, ReaderOptions{Keys: newTestKeys()}).Read(new(bytes.Buffer))
+	if err == nil || err == ErrTampered {
+		t.Errorf("Expected a checksum failure but got '%v'", err)
+	}
+}
+
+func TestEncryption_Error(t *testing.T) {
+	encrypted := writeEncryptedLog(t, newTestKeys(), NoCompression, []byte("first"))
+	fs, f := newTestLog(t)
+	writeFailOnError(t, NewRecordWriter(f, 0), []byte("first"))
+	plain := readTestLog(t, fs)
+
+	tests := map[string]struct {
+		log           []byte
+		opts          ReaderOptions
+		expectedError error
+	}{
+		"Encrypted log without keys": {
+			log:           encrypted,
+			expectedError: errorEncryptedLog,
+		},
+		"Plain log with keys": {
+			log:           plain,
+			opts:          ReaderOptions{Keys: newTestKeys()},
+			expectedError: errorMissingPreamble,
+		},
+		"Empty log with keys": {
+			log:           nil,
+			opts:          ReaderOptions{Keys: newTestKeys()},
+			expectedError: io.EOF,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := NewRecordReaderWithOptions(bytes.NewReader(test.log), 0, test.opts).Read(new(bytes.Buffer))
+			if err != test.expectedError {
+				t.Errorf("Expected '%v' but got '%v'", test.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestEncryption_WriterError(t *testing.T) {
+	tests := map[string]struct {
+		destLength    int64
+		opts          WriterOptions
+		expectedError error
+	}{
+		"Recyclable": {
+			opts:          WriterOptions{Keys: newTestKeys(), Recyclable: true},
+			expectedError: errorEncryptionInRecyclableLog,
+		},
+		"Append": {
+			destLength:    100,
+			opts:          WriterOptions{Keys: newTestKeys()},
+			expectedError: errorEncryptedAppend,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, f := newTestLog(t)
+			w := NewRecordWriterWithOptions(f, test.destLength, test.opts)
+			if _, err := w.Write([]byte("hello")); err != test.expectedError {
+				t.Errorf("Expected '%v' but got '%v'", test.expectedError, err)
+			}
+		})
+	}
+}
+
+// rechecksum recomputes the checksum of the fragment at the start of buf.
+func rechecksum(buf []byte) {
+	h := header(buf[:recordHeaderSize])
+	h.SetChecksum(crc32c.Mask(fragmentChecksum(crc32c.New(), h, buf[recordHeaderSize:recordHeaderSize+int(h.Length())])))
+}
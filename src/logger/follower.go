@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrCaughtUp is returned by Follower.TryNext when every record written so far has been read.
+var ErrCaughtUp = errors.New("logger: caught up with the records written so far")
+
+// Follower reads records from a log that a RecordWriter is still appending to.
+//
+// Unlike a RecordReader, a Follower takes the end of src to be the end of the data written so far. A
+// record that is only partly written is never returned; its fragments are kept until the rest of it
+// can be read. Damaged fragments are handled as configured by the ReaderOptions.
+type Follower struct {
+	rr           *RecordReader
+	pollInterval time.Duration
+}
+
+// NewFollower creates a Follower that returns the records of src starting at or after initialOffset,
+// and that checks src for new data every pollInterval once it has caught up.
+func NewFollower(src io.ReadSeeker, initialOffset int64, opts ReaderOptions, pollInterval time.Duration) *Follower {
+	rr := NewRecordReaderWithOptions(src, initialOffset, opts)
+	rr.follow = true
+	return &Follower{rr: rr, pollInterval: pollInterval}
+}
+
+// TryNext returns the next record and its offset without waiting. It returns ErrCaughtUp when that
+// record has not been completely written yet.
+//
+// The record is only valid until the next call to TryNext or Next.
+func (f *Follower) TryNext() ([]byte, int64, error) {
+	// Whatever was missing at the last attempt may have been written since.
+	f.rr.eof = false
+	return f.rr.readRecord()
+}
+
+// Next returns the next record and its offset, waiting for it to be written if needed.
+// It returns ctx.Err() when ctx is done first.
+//
+// The record is only valid until the next call to TryNext or Next.
+func (f *Follower) Next(ctx context.Context) ([]byte, int64, error) {
+	var timer *time.Timer
+	for {
+		record, offset, err := f.TryNext()
+		if err != ErrCaughtUp {
+			return record, offset, err
+		}
+
+		if timer == nil {
+			timer = time.NewTimer(f.pollInterval)
+			defer timer.Stop()
+		} else {
+			timer.Reset(f.pollInterval)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFollower_TryNext_ShouldOnlyReturnCompletelyWrittenRecords(t *testing.T) {
+	fs, log := newTestLog(t)
+	w := NewRecordWriter(log, 0)
+	records := [][]byte{[]byte("first"), repeat(50, 3*blockSize), []byte("third"), repeat(51, blockSize-62), []byte("fifth")}
+	for _, record := range records {
+		writeFailOnError(t, w, record)
+	}
+	written := readTestLog(t, fs)
+
+	for _, chunkSize := range []int{1, recordHeaderSize, 1000, blockSize} {
+		t.Run(fmt.Sprintf("Appending %v bytes at a time", chunkSize), func(t *testing.T) {
+			// The follower reads the file while the test appends to it.
+			srcFS, src := newTestLog(t)
+			f := NewFollower(openTestLog(t, srcFS), 0, ReaderOptions{}, time.Millisecond)
+
+			var read [][]byte
+			for data := written; len(data) > 0; {
+				n := chunkSize
+				if n > len(data) {
+					n = len(data)
+				}
+				src.Write(data[:n])
+				data = data[n:]
+
+				for {
+					record, _, err := f.TryNext()
+					if err == ErrCaughtUp {
+						break
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+					read = append(read, append([]byte(nil), record...))
+				}
+			}
+
+			if !reflect.DeepEqual(read, records) {
+				t.Errorf("Expected %v records to be read in full but got %v records", len(records), len(read))
+			}
+		})
+	}
+}
+
+func TestFollower_Next_ShouldWaitForTheRecordToBeWritten(t *testing.T) {
+	fs, log := newTestLog(t)
+	writeFailOnError(t, NewRecordWriter(log, 0), []byte("hello world"))
+	written := readTestLog(t, fs)
+
+	srcFS, src := newTestLog(t)
+	src.Write(written[:recordHeaderSize+2])
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		src.Write(written[recordHeaderSize+2:])
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	record, offset, err := NewFollower(openTestLog(t, srcFS), 0, ReaderOptions{}, time.Millisecond).Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(record) != "hello world" || offset != 0 {
+		t.Errorf("Expected ('hello world', 0) but got ('%s', %v)", record, offset)
+	}
+}
+
+func TestFollower_Next_ShouldStopWhenTheContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	fs, _ := newTestLog(t)
+	if _, _, err := NewFollower(openTestLog(t, fs), 0, ReaderOptions{}, time.Millisecond).Next(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected '%v' but got '%v'", context.DeadlineExceeded, err)
+	}
+}
+
+func TestFollower_ReusedFile_ShouldWaitForTheOldRecordsToBeOverwritten(t *testing.T) {
+	oldLog := writeRecyclableLog(t, 1, 20, 500)
+	newLog := writeRecyclableLog(t, 2, 3, 800)
+
+	fs, src := newTestLog(t)
+	src.Write(oldLog)
+	f := NewFollower(openTestLog(t, fs), 0, ReaderOptions{LogNumber: 2}, time.Millisecond)
+
+	var read int
+	for written := 0; written < len(newLog); written += 100 {
+		if _, _, err := f.TryNext(); err != ErrCaughtUp {
+			t.Fatalf("Expected '%v' before the next record is written but got '%v'", ErrCaughtUp, err)
+		}
+		// The new log overwrites the old one from the start of the file.
+		src.Seek(0, io.SeekStart)
+		src.Write(newLog[:written+100])
+
+		for {
+			record, _, err := f.TryNext()
+			if err == ErrCaughtUp {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := fmt.Sprintf("log 2 record %v", read); string(record[:len(expected)]) != expected {
+				t.Fatalf("Expected '%v' but got '%s'", expected, record[:len(expected)])
+			}
+			read++
+		}
+	}
+	if read != 3 {
+		t.Errorf("Expected 3 records but got %v", read)
+	}
+}
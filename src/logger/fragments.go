@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"hash"
+	"io"
+
+	"crc32c"
+)
+
+// FragmentKind tells what the bytes of a Fragment are.
+type FragmentKind uint8
+
+const (
+	// RecordFragment is a fragment with a header, as written by RecordWriter.
+	RecordFragment FragmentKind = iota
+	// Padding is the trailer at the end of a block that is too short for a header, or zero filled space
+	// that readers skip to the end of the block.
+	Padding
+	// Skipped is space that a RecordReader drops because it is damaged or cut short.
+	Skipped
+)
+
+func (k FragmentKind) String() string {
+	switch k {
+	case RecordFragment:
+		return "fragment"
+	case Padding:
+		return "padding"
+	case Skipped:
+		return "skipped"
+	default:
+		return "Invalid FragmentKind"
+	}
+}
+
+// Fragment is a piece of the physical layout of a log, as found by ScanFragments.
+type Fragment struct {
+	Kind   FragmentKind
+	Offset int64
+	Block  int64
+	// Length is the length of the data of a RecordFragment, and the number of bytes covered by Padding
+	// and Skipped.
+	Length int
+
+	// Type, LogNumber and ChecksumOK describe the header of a RecordFragment. LogNumber is only set for
+	// the recyclable record types.
+	Type       string
+	LogNumber  uint32
+	ChecksumOK bool
+
+	// Data is the data of a RecordFragment or the bytes covered by Padding and Skipped. It is only valid
+	// during the call it is passed to.
+	Data []byte
+	// Reason is why Skipped bytes are dropped.
+	Reason error
+}
+
+// ScanFragments calls fn with each fragment of the log in src in order, along with the padding and the
+// space that a RecordReader would skip. It stops at the first error returned by fn.
+//
+// A RecordReader drops the rest of the block after a fragment whose checksum is not OK, since its length
+// cannot be trusted. The fragment is still passed to fn, followed by the rest of the block as Skipped.
+func ScanFragments(src io.Reader, fn func(Fragment) error) error {
+	block := make([]byte, blockSize)
+	hash := crc32c.New()
+	for blockStart := int64(0); ; blockStart += blockSize {
+		n, err := io.ReadFull(src, block)
+		switch err {
+		case nil, io.ErrUnexpectedEOF:
+		case io.EOF:
+			return nil
+		default:
+			return err
+		}
+		if err := scanBlock(block[:n], blockStart, hash, fn); err != nil {
+			return err
+		}
+		if n < blockSize {
+			return nil
+		}
+	}
+}
+
+// scanBlock calls fn with the fragments of buf, which is the block at blockStart. It is shorter than
+// blockSize when src ended within the block.
+func scanBlock(buf []byte, blockStart int64, crc hash.Hash32, fn func(Fragment) error) error {
+	complete := len(buf) == blockSize
+	for pos := 0; pos < len(buf); {
+		rest := buf[pos:]
+		f := Fragment{Offset: blockStart + int64(pos), Block: blockStart / blockSize, Length: len(rest), Data: rest}
+		headerSize := headerSizeOf(rest)
+
+		var h header
+		if len(rest) >= headerSize {
+			h = header(rest[:headerSize])
+		}
+		switch {
+		case h == nil && complete:
+			f.Kind = Padding
+		case h == nil:
+			f.Kind, f.Reason = Skipped, errorHeaderEOF
+		case h.RecordType() == uninit && h.Length() == 0:
+			f.Kind = Padding
+		case headerSize+int(h.Length()) > len(rest) && complete:
+			f.Kind, f.Reason = Skipped, errorBadRecordLength
+		case headerSize+int(h.Length()) > len(rest):
+			f.Kind, f.Reason = Skipped, errorBodyEOF
+		default:
+			f.Kind, f.Type = RecordFragment, h.RecordType().String()
+			f.Length = int(h.Length())
+			f.Data = rest[headerSize : headerSize+f.Length]
+			if h.RecordType().isRecyclable() {
+				f.LogNumber = h.LogNumber()
+			}
+			checksum := crc32c.Mask(fragmentChecksum(crc, h, f.Data))
+			f.ChecksumOK = h.Checksum() == checksum
+			if err := fn(f); err != nil {
+				return err
+			}
+
+			pos += headerSize + f.Length
+			if !f.ChecksumOK && pos < len(buf) {
+				return fn(Fragment{
+					Kind:   Skipped,
+					Offset: blockStart + int64(pos),
+					Block:  f.Block,
+					Length: len(buf) - pos,
+					Data:   buf[pos:],
+					Reason: checksumError(h.Checksum(), checksum),
+				})
+			}
+			continue
+		}
+		return fn(f)
+	}
+	return nil
+}
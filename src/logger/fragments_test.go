@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type scannedFragment struct {
+	kind   FragmentKind
+	offset int64
+	length int
+	typ    string
+	reason error
+}
+
+func scanFragments(t *testing.T, log []byte) []scannedFragment {
+	var fragments []scannedFragment
+	err := ScanFragments(bytes.NewReader(log), func(f Fragment) error {
+		fragments = append(fragments, scannedFragment{f.Kind, f.Offset, f.Length, f.Type, f.Reason})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fragments
+}
+
+func TestScanFragments(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	writeFailOnError(t, w, []byte("hello"))
+	writeFailOnError(t, w, repeat(50, blockSize-2*recordHeaderSize-5-3))
+	writeFailOnError(t, w, []byte("world"))
+	log := readTestLog(t, fs)
+
+	t.Run("Should show the trailer", func(t *testing.T) {
+		expected := []scannedFragment{
+			{RecordFragment, 0, 5, "FULL", nil},
+			{RecordFragment, 12, blockSize - 2*recordHeaderSize - 5 - 3, "FULL", nil},
+			{Padding, blockSize - 3, 3, "", nil},
+			{RecordFragment, blockSize, 5, "FULL", nil},
+		}
+		if actual := scanFragments(t, log); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected %v but got %v", expected, actual)
+		}
+	})
+
+	t.Run("Should show a truncated fragment as skipped", func(t *testing.T) {
+		expected := []scannedFragment{
+			{RecordFragment, 0, 5, "FULL", nil},
+			{Skipped, 12, 10, "", errorBodyEOF},
+		}
+		if actual := scanFragments(t, log[:22]); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected %v but got %v", expected, actual)
+		}
+	})
+
+	t.Run("Should show zero filled space as padding", func(t *testing.T) {
+		zeroed := append(append([]byte{}, log[:12]...), make([]byte, 100)...)
+		expected := []scannedFragment{
+			{RecordFragment, 0, 5, "FULL", nil},
+			{Padding, 12, 100, "", nil},
+		}
+		if actual := scanFragments(t, zeroed); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected %v but got %v", expected, actual)
+		}
+	})
+}
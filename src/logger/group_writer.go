@@ -0,0 +1,114 @@
+package logger
+
+import "sync"
+
+const (
+	// maxGroupSize is the most bytes of records a leader writes in one group.
+	maxGroupSize = 1 << 20
+	// smallGroupWrite is the size under which a leader limits its group to smallGroupWrite more
+	// bytes, so that a small write is not slowed down too much by those behind it.
+	smallGroupWrite = 128 << 10
+)
+
+// GroupWriter lets many goroutines write records to a RecordWriter at the same time.
+//
+// Concurrent writes queue up behind each other. The write at the head of the queue leads: it writes
+// the records of the writes queued behind it along with its own as consecutive records, flushes them
+// with a single Sync when any of them asked for one, and then hands each write its own result. This
+// is the group commit of LevelDB's DB::Write.
+type GroupWriter struct {
+	mu    sync.Mutex
+	w     *RecordWriter
+	queue []*groupWrite
+}
+
+type groupWrite struct {
+	record []byte
+	sync   bool
+
+	done   bool
+	offset int64
+	err    error
+	cond   *sync.Cond
+}
+
+// NewGroupWriter creates a GroupWriter on top of w. Nothing else should write to w afterwards.
+func NewGroupWriter(w *RecordWriter) *GroupWriter {
+	return &GroupWriter{w: w}
+}
+
+// Write writes record p and returns the offset of its first fragment. The record has been flushed to
+// the RecordWriter's dest when Write returns, and synced if opts asks for it.
+func (g *GroupWriter) Write(p []byte, opts WriteOptions) (int64, error) {
+	gw := &groupWrite{record: p, sync: opts.Sync, cond: sync.NewCond(&g.mu)}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.queue = append(g.queue, gw)
+	for !gw.done && gw != g.queue[0] {
+		gw.cond.Wait()
+	}
+	if gw.done {
+		return gw.offset, gw.err
+	}
+
+	group := g.buildGroup()
+
+	// Only the leader touches w, so the queue can take new writes while the group is written.
+	g.mu.Unlock()
+	err := g.writeGroup(group)
+	g.mu.Lock()
+
+	for _, member := range group {
+		if member.err == nil {
+			member.err = err
+		}
+		member.done = true
+		member.cond.Signal()
+	}
+	g.queue = g.queue[len(group):]
+	if len(g.queue) > 0 {
+		g.queue[0].cond.Signal()
+	}
+	return gw.offset, gw.err
+}
+
+// buildGroup returns the writes from the head of the queue that the leader writes together. A write
+// that asks for a sync is left out of a group led by one that does not, so that the leader does not
+// pay for a sync it did not ask for.
+func (g *GroupWriter) buildGroup() []*groupWrite {
+	leader := g.queue[0]
+	size := len(leader.record)
+	maxSize := maxGroupSize
+	if size <= smallGroupWrite {
+		maxSize = size + smallGroupWrite
+	}
+
+	n := 1
+	for ; n < len(g.queue); n++ {
+		next := g.queue[n]
+		if next.sync && !leader.sync {
+			break
+		}
+		if size += len(next.record); size > maxSize {
+			break
+		}
+	}
+	return g.queue[:n]
+}
+
+func (g *GroupWriter) writeGroup(group []*groupWrite) error {
+	needsSync := false
+	for _, member := range group {
+		if _, err := g.w.Write(member.record); err != nil {
+			return err
+		}
+		member.offset = g.w.LastRecordOffset()
+		needsSync = needsSync || member.sync
+	}
+
+	if needsSync {
+		return g.w.Sync()
+	}
+	return g.w.Flush()
+}
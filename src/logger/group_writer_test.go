@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+
+	"env"
+)
+
+// blockingSyncFile is a file whose Sync blocks while the test holds syncMu.
+type blockingSyncFile struct {
+	env.File
+	syncMu sync.Mutex
+}
+
+func (f *blockingSyncFile) Sync() error {
+	f.syncMu.Lock()
+	defer f.syncMu.Unlock()
+	return f.File.Sync()
+}
+
+func TestGroupWriter_ConcurrentWrites_ShouldReturnTheOffsetOfEachRecord(t *testing.T) {
+	fs, f := newTestLog(t)
+	g := NewGroupWriter(NewRecordWriter(f, 0))
+
+	const writers, writesPerWriter = 16, 50
+	var mu sync.Mutex
+	written := make(map[int64]string)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < writesPerWriter; j++ {
+				record := fmt.Sprintf("writer %v record %v %s", i, j, bytes.Repeat([]byte("x"), 100*j))
+				offset, err := g.Write([]byte(record), WriteOptions{Sync: j%2 == 0})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				written[offset] = record
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	rr := NewRecordReader(openTestLog(t, fs), 0)
+	var read int
+	for ; rr.Next(); read++ {
+		if expected := written[rr.Offset()]; string(rr.Record()) != expected {
+			t.Fatalf("Expected '%v' at offset %v but got '%s'", expected, rr.Offset(), rr.Record())
+		}
+	}
+	if rr.Err() != nil || read != writers*writesPerWriter {
+		t.Errorf("Expected (%v records, %v) but got (%v records, %v)", writers*writesPerWriter, nil, read, rr.Err())
+	}
+}
+
+func TestGroupWriter_WritesQueuedBehindASync_ShouldShareTheNextSync(t *testing.T) {
+	fs, f := newTestLog(t)
+	log := &blockingSyncFile{File: f}
+	g := NewGroupWriter(NewRecordWriter(log, 0))
+
+	// The first write leads on its own and blocks in Sync while the others queue up behind it.
+	log.syncMu.Lock()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.Write([]byte("leader"), WriteOptions{Sync: true})
+	}()
+	waitForQueueLength(g, 1)
+
+	const followers = 10
+	for i := 0; i < followers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g.Write([]byte(fmt.Sprintf("follower %v", i)), WriteOptions{Sync: true})
+		}(i)
+	}
+	waitForQueueLength(g, 1+followers)
+	log.syncMu.Unlock()
+	wg.Wait()
+
+	if fs.Syncs() != 2 {
+		t.Errorf("Expected 2 syncs for %v writes but got %v", 1+followers, fs.Syncs())
+	}
+}
+
+func TestGroupWriter_ShouldNotSyncForWritesThatDidNotAskForIt(t *testing.T) {
+	fs, f := newTestLog(t)
+	g := NewGroupWriter(NewRecordWriter(f, 0))
+
+	if _, err := g.Write([]byte("hello"), WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(readTestLog(t, fs)); fs.Syncs() != 0 || n != recordHeaderSize+len("hello") {
+		t.Errorf("Expected (syncs=0, len=%v) but got (syncs=%v, len=%v)", recordHeaderSize+len("hello"), fs.Syncs(), n)
+	}
+}
+
+func TestGroupWriter_EmptyRecords_ShouldHaveTheirOwnOffsets(t *testing.T) {
+	fs, f := newTestLog(t)
+	g := NewGroupWriter(NewRecordWriter(f, 0))
+
+	var offsets []int64
+	for _, record := range []string{"", "", "hello"} {
+		offset, err := g.Write([]byte(record), WriteOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, offset)
+	}
+	if expected := []int64{0, recordHeaderSize, 2 * recordHeaderSize}; !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("Expected offsets %v but got %v", expected, offsets)
+	}
+
+	rr := NewRecordReader(openTestLog(t, fs), 0)
+	var records []string
+	for rr.Next() {
+		records = append(records, string(rr.Record()))
+	}
+	if expected := []string{"", "", "hello"}; rr.Err() != nil || !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected (%q, %v) but got (%q, %v)", expected, nil, records, rr.Err())
+	}
+}
+
+func waitForQueueLength(g *GroupWriter, n int) {
+	for {
+		g.mu.Lock()
+		queued := len(g.queue)
+		g.mu.Unlock()
+		if queued == n {
+			return
+		}
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,244 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"env"
+)
+
+// DefaultMaxLogSize is the size at which a LogManager starts a new log unless told otherwise.
+const DefaultMaxLogSize = 4 << 20
+
+// LogManagerOptions control the logs a LogManager creates and what happens to obsolete logs.
+type LogManagerOptions struct {
+	// MaxLogSize is the size after which the next record goes to a new log. A record is never split
+	// across logs, so a log can grow past it by one record. DefaultMaxLogSize is used when it is zero.
+	MaxLogSize int64
+
+	// Writer are the options every log is written with. The LogNumber of a recyclable log is set to
+	// its number.
+	Writer WriterOptions
+
+	// ArchiveDir is where obsolete logs are moved to. They are deleted when it is empty.
+	ArchiveDir string
+}
+
+// LogManager writes records to a series of numbered log files, NNNNNN.log, in a directory.
+//
+// The manager starts a new log, numbered one past the highest in the directory, on the first write and
+// whenever the current log has reached MaxLogSize. The logs found in the directory are never written
+// to again. They stay live, along with the logs the manager creates, until MarkObsolete is called for
+// them. It is safe to use a LogManager from multiple goroutines.
+type LogManager struct {
+	fs   env.FS
+	dir  string
+	opts LogManagerOptions
+
+	mu   sync.Mutex
+	live []uint64
+	next uint64
+
+	// current is the number of the log that file and w write to. file is nil until the first write.
+	current uint64
+	file    env.File
+	w       *RecordWriter
+	closed  bool
+}
+
+var errorLogManagerClosed = errors.New("logger: log manager is closed")
+var errorCurrentLogObsolete = errors.New("logger: the current log cannot be obsolete")
+
+// OpenLogManager opens the logs in dir on fs, creating dir if it does not exist.
+func OpenLogManager(fs env.FS, dir string, opts LogManagerOptions) (*LogManager, error) {
+	if opts.MaxLogSize == 0 {
+		opts.MaxLogSize = DefaultMaxLogSize
+	}
+	if err := fs.MkdirAll(dir); err != nil {
+		return nil, err
+	}
+	if opts.ArchiveDir != "" {
+		if err := fs.MkdirAll(opts.ArchiveDir); err != nil {
+			return nil, err
+		}
+	}
+
+	live, err := listLogs(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	next := uint64(1)
+	if len(live) > 0 {
+		next = live[len(live)-1] + 1
+	}
+	return &LogManager{fs: fs, dir: dir, opts: opts, live: live, next: next}, nil
+}
+
+// LogFileName returns the name of the log numbered number in dir.
+func LogFileName(dir string, number uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%06d.log", number))
+}
+
+// parseLogFileName returns the number of the log named name, which is the base name of a file.
+func parseLogFileName(name string) (uint64, bool) {
+	digits := strings.TrimSuffix(name, ".log")
+	if digits == name || len(digits) < 6 || strings.TrimLeft(digits, "0123456789") != "" {
+		return 0, false
+	}
+	number, err := strconv.ParseUint(digits, 10, 64)
+	return number, err == nil
+}
+
+// listLogs returns the numbers of the logs in dir in increasing order.
+func listLogs(fs env.FS, dir string) ([]uint64, error) {
+	names, err := fs.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var numbers []uint64
+	for _, name := range names {
+		if number, ok := parseLogFileName(name); ok {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers, nil
+}
+
+// Write writes record p to the current log, starting a new log first when needed. It returns the number
+// of the log and the offset of the record in it.
+func (m *LogManager) Write(p []byte, opts WriteOptions) (uint64, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return 0, 0, errorLogManagerClosed
+	}
+
+	if m.file == nil || m.w.offset >= m.opts.MaxLogSize {
+		if err := m.roll(); err != nil {
+			return 0, 0, err
+		}
+	}
+	if _, err := m.w.WriteWithOptions(p, opts); err != nil {
+		return 0, 0, err
+	}
+	return m.current, m.w.LastRecordOffset(), nil
+}
+
+// Roll makes the next write go to a new log, whatever the size of the current one. It returns the
+// number of that log. The logs before it can be marked obsolete once their records are safely stored
+// somewhere else.
+func (m *LogManager) Roll() (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return 0, errorLogManagerClosed
+	}
+	if err := m.roll(); err != nil {
+		return 0, err
+	}
+	return m.current, nil
+}
+
+// roll syncs and closes the current log and creates the next one.
+func (m *LogManager) roll() error {
+	if err := m.closeCurrent(); err != nil {
+		return err
+	}
+
+	number := m.next
+	name := LogFileName(m.dir, number)
+	f, err := m.fs.Create(name)
+	if err != nil {
+		return err
+	}
+	opts := m.opts.Writer
+	opts.LogNumber = uint32(number)
+	w := NewRecordWriterWithOptions(f, 0, opts)
+	if w.err != nil {
+		f.Close()
+		m.fs.Remove(name)
+		return w.err
+	}
+	if err := m.fs.SyncDir(m.dir); err != nil {
+		f.Close()
+		m.fs.Remove(name)
+		return err
+	}
+
+	m.next++
+	m.live = append(m.live, number)
+	m.current, m.file, m.w = number, f, w
+	return nil
+}
+
+func (m *LogManager) closeCurrent() error {
+	if m.file == nil {
+		return nil
+	}
+	err := m.w.Sync()
+	if cerr := m.file.Close(); err == nil {
+		err = cerr
+	}
+	m.file, m.w = nil, nil
+	return err
+}
+
+// Sync flushes the records written so far and commits the current log to stable storage.
+func (m *LogManager) Sync() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.file == nil {
+		return nil
+	}
+	return m.w.Sync()
+}
+
+// Logs returns the numbers of the live logs in increasing order. The last is the current log once
+// anything has been written.
+func (m *LogManager) Logs() []uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]uint64(nil), m.live...)
+}
+
+// MarkObsolete marks the logs numbered before number as obsolete. They are moved to ArchiveDir, or
+// deleted when there is none. The current log cannot be obsolete.
+func (m *LogManager) MarkObsolete(number uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.file != nil && number > m.current {
+		return errorCurrentLogObsolete
+	}
+
+	for len(m.live) > 0 && m.live[0] < number {
+		name := LogFileName(m.dir, m.live[0])
+		var err error
+		if m.opts.ArchiveDir != "" {
+			err = m.fs.Rename(name, filepath.Join(m.opts.ArchiveDir, filepath.Base(name)))
+		} else {
+			err = m.fs.Remove(name)
+		}
+		if err != nil {
+			return err
+		}
+		m.live = m.live[1:]
+	}
+	return nil
+}
+
+// Close syncs and closes the current log. Logs cannot be written afterwards.
+func (m *LogManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return errorLogManagerClosed
+	}
+	m.closed = true
+	return m.closeCurrent()
+}
@@ -0,0 +1,36 @@
+// +build integration
+
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"env"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir(os.TempDir(), "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLogManager_OS(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	m, err := OpenLogManager(env.OS, dir, LogManagerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.Write([]byte("hello world"), WriteOptions{Sync: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	readRecordAndVerify(t, readLogFile(t, env.OS, LogFileName(dir, 1)), []byte("hello world"))
+}
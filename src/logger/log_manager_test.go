@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"env"
+)
+
+func readLogFile(t *testing.T, fs env.FS, name string) *RecordReader {
+	f, err := fs.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRecordReader(bytes.NewReader(b), 0)
+}
+
+func TestParseLogFileName(t *testing.T) {
+	tests := map[string]struct {
+		number uint64
+		ok     bool
+	}{
+		"000001.log":  {1, true},
+		"123456.log":  {123456, true},
+		"1234567.log": {1234567, true},
+		"1.log":       {0, false},
+		"000001.sst":  {0, false},
+		"000001":      {0, false},
+		"00000a.log":  {0, false},
+		"-00001.log":  {0, false},
+		"LOCK":        {0, false},
+	}
+
+	for name, test := range tests {
+		number, ok := parseLogFileName(name)
+		if number != test.number || ok != test.ok {
+			t.Errorf("Expected %v, %v for %v but got %v, %v", test.number, test.ok, name, number, ok)
+		}
+	}
+}
+
+func TestLogFileName(t *testing.T) {
+	if name := LogFileName("db", 12); name != "db/000012.log" {
+		t.Errorf("Expected 'db/000012.log' but got '%v'", name)
+	}
+}
+
+func TestLogManager_EmptyRecords_ShouldHaveTheirOwnOffsets(t *testing.T) {
+	fs := env.NewMemFS()
+	m, err := OpenLogManager(fs, "db", LogManagerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offsets []int64
+	for _, record := range []string{"", "", "hello"} {
+		_, offset, err := m.Write([]byte(record), WriteOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, offset)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []int64{0, recordHeaderSize, 2 * recordHeaderSize}; !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("Expected offsets %v but got %v", expected, offsets)
+	}
+	rr := readLogFile(t, fs, LogFileName("db", 1))
+	var records []string
+	for rr.Next() {
+		records = append(records, string(rr.Record()))
+	}
+	if expected := []string{"", "", "hello"}; rr.Err() != nil || !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected (%q, %v) but got (%q, %v)", expected, nil, records, rr.Err())
+	}
+}
+
+func TestLogManager_ShouldRollToANewLogAtMaxLogSize(t *testing.T) {
+	fs := env.NewMemFS()
+	m, err := OpenLogManager(fs, "db", LogManagerOptions{MaxLogSize: 2 * blockSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := repeat(50, blockSize)
+	var numbers []uint64
+	for i := 0; i < 4; i++ {
+		number, _, err := m.Write(record, WriteOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, number)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []uint64{1, 1, 2, 2}; !reflect.DeepEqual(numbers, expected) {
+		t.Errorf("Expected %v but got %v", expected, numbers)
+	}
+	if expected := []uint64{1, 2}; !reflect.DeepEqual(m.Logs(), expected) {
+		t.Errorf("Expected %v but got %v", expected, m.Logs())
+	}
+	rr := readLogFile(t, fs, LogFileName("db", 2))
+	readRecordAndVerify(t, rr, record)
+	readRecordAndVerify(t, rr, record)
+	verifyEOF(t, rr)
+}
+
+func TestLogManager_Open_ShouldContinueAfterTheExistingLogs(t *testing.T) {
+	fs := env.NewMemFS()
+	fs.MkdirAll("db")
+	for _, name := range []string{"000003.log", "000007.log", "000009.sst", "LOCK"} {
+		f, err := fs.Create(filepath.Join("db", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+
+	m, err := OpenLogManager(fs, "db", LogManagerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if number, err := m.Roll(); err != nil || number != 8 {
+		t.Errorf("Expected to roll to log 8 but got %v, %v", number, err)
+	}
+	if expected := []uint64{3, 7, 8}; !reflect.DeepEqual(m.Logs(), expected) {
+		t.Errorf("Expected %v but got %v", expected, m.Logs())
+	}
+}
+
+func TestLogManager_MarkObsolete(t *testing.T) {
+	tests := map[string]struct {
+		archive bool
+	}{
+		"Should delete obsolete logs":                 {archive: false},
+		"Should move obsolete logs to the ArchiveDir": {archive: true},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			fs := env.NewMemFS()
+			opts := LogManagerOptions{}
+			if test.archive {
+				opts.ArchiveDir = "archive"
+			}
+
+			m, err := OpenLogManager(fs, "db", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+			for i := 0; i < 3; i++ {
+				if _, err := m.Roll(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := m.MarkObsolete(3); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.MarkObsolete(4); err != errorCurrentLogObsolete {
+				t.Errorf("Expected '%v' but got '%v'", errorCurrentLogObsolete, err)
+			}
+			if expected := []uint64{3}; !reflect.DeepEqual(m.Logs(), expected) {
+				t.Errorf("Expected %v but got %v", expected, m.Logs())
+			}
+			if logs, _ := listLogs(fs, "db"); !reflect.DeepEqual(logs, []uint64{3}) {
+				t.Errorf("Expected only log 3 in the directory but got %v", logs)
+			}
+			if test.archive {
+				if logs, _ := listLogs(fs, opts.ArchiveDir); !reflect.DeepEqual(logs, []uint64{1, 2}) {
+					t.Errorf("Expected logs 1 and 2 in the archive but got %v", logs)
+				}
+			}
+		})
+	}
+}
+
+func TestLogManager_Write_AfterClose_ShouldBeAnError(t *testing.T) {
+	m, err := OpenLogManager(env.NewMemFS(), "db", LogManagerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	if _, _, err := m.Write([]byte("hello"), WriteOptions{}); err != errorLogManagerClosed {
+		t.Errorf("Expected '%v' but got '%v'", errorLogManagerClosed, err)
+	}
+}
@@ -10,6 +10,8 @@ const (
 	blockSize = 32 * 1024
 	// recordHeaderSize is the size of the header for a record. Header has Checksum(uint32), Length(uint16) and RecordType (uint8)
 	recordHeaderSize = 4 + 2 + 1
+	// recyclableRecordHeaderSize is the size of the header for a recyclable record, which adds the LogNumber (uint32).
+	recyclableRecordHeaderSize = recordHeaderSize + 4
 )
 
 type recordType uint8
@@ -26,6 +28,22 @@ func (r recordType) String() string {
 		return "MIDDLE"
 	case LAST:
 		return "LAST"
+	case RecyclableFULL:
+		return "RecyclableFULL"
+	case RecyclableFIRST:
+		return "RecyclableFIRST"
+	case RecyclableMIDDLE:
+		return "RecyclableMIDDLE"
+	case RecyclableLAST:
+		return "RecyclableLAST"
+	case PREAMBLE:
+		return "PREAMBLE"
+	}
+	switch {
+	case r.isMetadata():
+		return fmt.Sprintf("Metadata(%d)", r.metadataType())
+	case r.isCompressed() && r.isKnown():
+		return "Compressed" + (r &^ compressedFlag).String()
 	default:
 		return fmt.Sprintf("Invalid recordType %v", int(r))
 	}
@@ -41,12 +59,98 @@ const (
 	MIDDLE
 	// LAST is the type of record that contains last fragment of a user record.
 	LAST
+
+	// RecyclableFULL, RecyclableFIRST, RecyclableMIDDLE and RecyclableLAST are FULL, FIRST, MIDDLE and LAST
+	// for logs written to reused files. Their header carries the number of the log they belong to, so
+	// that records left over from an earlier use of the file can be told apart.
+	RecyclableFULL
+	RecyclableFIRST
+	RecyclableMIDDLE
+	RecyclableLAST
+
+	// PREAMBLE is the type of the record at the start of an encrypted log that identifies its key.
+	PREAMBLE
+)
+
+// MetadataType identifies a user defined metadata record, such as a tracing marker.
+//
+// Metadata records are stored with record types MinMetadataType to MaxMetadataType offset by
+// metadataRecordTypes, a range that is reserved for them. Readers that do not know about metadata
+// records can skip them like any other record type they do not understand.
+type MetadataType uint8
+
+const (
+	MinMetadataType MetadataType = 0
+	MaxMetadataType MetadataType = 63
+
+	metadataRecordTypes recordType = 64
 )
 
+func (t MetadataType) recordType() recordType {
+	return metadataRecordTypes + recordType(t)
+}
+
+func (r recordType) isMetadata() bool {
+	return r >= MinMetadataType.recordType() && r <= MaxMetadataType.recordType()
+}
+
+func (r recordType) metadataType() MetadataType {
+	return MetadataType(r - metadataRecordTypes)
+}
+
+// compressedFlag is set in the type of the FULL or FIRST fragment of a compressed user record. Readers
+// that do not know about compression do not know these types either, so they reject the record.
+const compressedFlag recordType = 0x80
+
+// isKnown reports whether r is one of the types that user records are stored with.
+func (r recordType) isKnown() bool {
+	switch r.base() {
+	case FULL, FIRST:
+		return true
+	case MIDDLE, LAST:
+		return !r.isCompressed()
+	default:
+		return false
+	}
+}
+
+func (r recordType) isCompressed() bool {
+	return r&compressedFlag != 0
+}
+
+func (r recordType) isRecyclable() bool {
+	r &^= compressedFlag
+	return r >= RecyclableFULL && r <= RecyclableLAST
+}
+
+// base returns FULL, FIRST, MIDDLE or LAST for the plain, recyclable and compressed types.
+func (r recordType) base() recordType {
+	if r.isRecyclable() {
+		r = r&^compressedFlag - RecyclableFULL + FULL
+	}
+	return r &^ compressedFlag
+}
+
+func (r recordType) recyclable() recordType {
+	return r - FULL + RecyclableFULL
+}
+
 type header []byte
 
 func newHeader() header {
-	return make([]byte, 7)
+	return make([]byte, recordHeaderSize)
+}
+
+func newRecyclableHeader() header {
+	return make([]byte, recyclableRecordHeaderSize)
+}
+
+// headerSizeOf returns the size of the header at the start of buf, which depends on its record type.
+func headerSizeOf(buf []byte) int {
+	if len(buf) >= recordHeaderSize && recordType(buf[6]).isRecyclable() {
+		return recyclableRecordHeaderSize
+	}
+	return recordHeaderSize
 }
 
 func (h header) SetRecordType(rt recordType) {
@@ -61,6 +165,20 @@ func (h header) RecordTypeByte() []byte {
 	return h[6:7]
 }
 
+// ChecksummedBytes returns the part of the header covered by the checksum: the record type and,
+// for recyclable records, the log number.
+func (h header) ChecksummedBytes() []byte {
+	return h[6:]
+}
+
+func (h header) SetLogNumber(n uint32) {
+	binary.LittleEndian.PutUint32(h[7:11], n)
+}
+
+func (h header) LogNumber() uint32 {
+	return binary.LittleEndian.Uint32(h[7:11])
+}
+
 func (h header) SetLength(l uint16) {
 	binary.LittleEndian.PutUint16(h[4:6], l)
 }
@@ -1,41 +1,59 @@
 package logger
 
-import "bytes"
+import (
+	"io/ioutil"
+	"testing"
 
-type OnlyOnceSeeker struct {
-	alreadySeeked bool
-}
+	"env"
+)
 
-func (s *OnlyOnceSeeker) ResetSeeker() {
-	s.alreadySeeked = false
-}
+// testLogName is the name of the log file that tests write to.
+const testLogName = "000001.log"
 
-func (s *OnlyOnceSeeker) Seek(offset int64, whence int) (int64, error) {
-	if s.alreadySeeked {
-		panic("Should be seeked only once. But was called more than once")
+// newTestLog creates an empty log file in a new MemFS. The FaultyFS around it lets tests count syncs
+// and fail writes.
+func newTestLog(t *testing.T) (*env.FaultyFS, env.File) {
+	fs := env.NewFaultyFS(env.NewMemFS(), 1)
+	f, err := fs.Create(testLogName)
+	if err != nil {
+		t.Fatal(err)
 	}
-	s.alreadySeeked = true
-	return offset, nil
+	return fs, f
 }
 
-type OnlyOnceSeekableBuffer struct {
-	bytes.Buffer
-	OnlyOnceSeeker
+// openTestLog opens the log file of fs for reading.
+func openTestLog(t *testing.T, fs env.FS) env.File {
+	f, err := fs.Open(testLogName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
 }
 
-type DiscardAfterBuffer struct {
-	OnlyOnceSeekableBuffer
-	N int
+// readTestLog returns the contents of the log file of fs.
+func readTestLog(t *testing.T, fs env.FS) []byte {
+	f := openTestLog(t, fs)
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
 }
 
-func (b *DiscardAfterBuffer) Write(p []byte) (int, error) {
-	last := len(p)
-	if last > b.N {
-		last = b.N
+// rewriteTestLog replaces the contents of the log file of fs with what change makes of them, to damage
+// the log in ways that a FaultyFS does not.
+func rewriteTestLog(t *testing.T, fs env.FS, change func(log []byte) []byte) {
+	data := change(readTestLog(t, fs))
+	if err := fs.Remove(testLogName); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.Create(testLogName)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if b.N > 0 {
-		n, _ := b.Buffer.Write(p[0:last])
-		b.N -= n
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
 	}
-	return len(p), nil
 }
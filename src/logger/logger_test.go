@@ -0,0 +1,35 @@
+package logger
+
+import "testing"
+
+func repeat(b byte, n int) []byte {
+	buf := make([]byte, n)
+	fill(buf, b)
+	return buf
+}
+
+func TestRecordType_String(t *testing.T) {
+	tests := map[recordType]string{
+		uninit:           "uninit",
+		FULL:             "FULL",
+		LAST:             "LAST",
+		RecyclableFULL:   "RecyclableFULL",
+		RecyclableFIRST:  "RecyclableFIRST",
+		RecyclableMIDDLE: "RecyclableMIDDLE",
+		RecyclableLAST:   "RecyclableLAST",
+		PREAMBLE:         "PREAMBLE",
+		recordType(42):   "Invalid recordType 42",
+		recordType(64):   "Metadata(0)",
+		recordType(127):  "Metadata(63)",
+		recordType(128):  "Invalid recordType 128",
+		recordType(0x81): "CompressedFULL",
+		recordType(0x86): "CompressedRecyclableFIRST",
+		recordType(0x83): "Invalid recordType 131",
+	}
+
+	for rt, expected := range tests {
+		if actual := rt.String(); actual != expected {
+			t.Errorf("Expected '%v' but got '%v'", expected, actual)
+		}
+	}
+}
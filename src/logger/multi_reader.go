@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+
+	"env"
+)
+
+// MultiReader reads the logs in a directory, as written by a LogManager, in number order as one
+// stream of records.
+//
+// The records of a log that end with it being cut short are what a writer that died mid-record leaves
+// behind. That is the clean end of the stream in the last log. Any other log was complete before the
+// next one was started, so a record cut short there is corruption: an error without a Reporter, or
+// reported as a TruncatedLogError in recovery mode.
+type MultiReader struct {
+	fs   env.FS
+	dir  string
+	opts ReaderOptions
+
+	logs []uint64
+	// current is the index in logs of the log that file and rr read.
+	current int
+	file    env.File
+	rr      *RecordReader
+
+	record []byte
+	offset int64
+	err    error
+}
+
+// TruncatedLogError is the corruption of a log other than the last one that ends within a record.
+type TruncatedLogError struct {
+	LogNumber uint64
+}
+
+func (e TruncatedLogError) Error() string {
+	return fmt.Sprintf("log %06d ends within a record", e.LogNumber)
+}
+
+// NewMultiReader creates a reader of the logs in dir on fs. Every log is read with opts, with the
+// LogNumber set to the number of the log.
+func NewMultiReader(fs env.FS, dir string, opts ReaderOptions) (*MultiReader, error) {
+	logs, err := listLogs(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &MultiReader{fs: fs, dir: dir, opts: opts, logs: logs, current: -1}, nil
+}
+
+// Next advances to the next record, which is then available through Record, LogNumber and Offset.
+// It returns false after the last record of the last log or when an error stops the reader, see Err.
+func (m *MultiReader) Next() bool {
+	for m.err == nil {
+		if m.rr == nil {
+			if m.current+1 == len(m.logs) {
+				m.err = io.EOF
+				break
+			}
+			m.err = m.open(m.current + 1)
+			continue
+		}
+
+		m.record, m.offset, m.err = m.rr.readRecord()
+		switch {
+		case m.err == nil:
+			return true
+		case m.current == len(m.logs)-1:
+			// A record cut short by the end of the last log is the clean end of the stream.
+			if m.err == errorHeaderEOF || m.err == errorBodyEOF {
+				m.err = io.EOF
+			}
+		case m.err == errorHeaderEOF || m.err == errorBodyEOF:
+			m.err = TruncatedLogError{m.logs[m.current]}
+		case m.err == io.EOF:
+			if m.rr.truncated > 0 {
+				m.rr.corrupt(m.rr.truncatedOffset, m.rr.truncated, TruncatedLogError{m.logs[m.current]})
+			}
+			m.err = m.closeFile()
+			m.rr = nil
+		}
+	}
+	return false
+}
+
+func (m *MultiReader) open(i int) error {
+	f, err := m.fs.Open(LogFileName(m.dir, m.logs[i]))
+	if err != nil {
+		return err
+	}
+	opts := m.opts
+	opts.LogNumber = uint32(m.logs[i])
+	m.current, m.file, m.rr = i, f, NewRecordReaderWithOptions(f, 0, opts)
+	return nil
+}
+
+func (m *MultiReader) closeFile() error {
+	if m.file == nil {
+		return nil
+	}
+	err := m.file.Close()
+	m.file = nil
+	return err
+}
+
+// Record returns the current record. It is only valid until the next call to Next.
+func (m *MultiReader) Record() []byte {
+	return m.record
+}
+
+// LogNumber returns the number of the log that the current record was read from. When Next fails it
+// is the number of the log that the error is in.
+func (m *MultiReader) LogNumber() uint64 {
+	if m.current < 0 {
+		return 0
+	}
+	return m.logs[m.current]
+}
+
+// Offset returns the offset of the first fragment of the current record in its log.
+func (m *MultiReader) Offset() int64 {
+	return m.offset
+}
+
+// Err returns the error that stopped Next. It is nil when Next stopped at the end of the last log.
+func (m *MultiReader) Err() error {
+	if m.err == io.EOF {
+		return nil
+	}
+	return m.err
+}
+
+// Close closes the log being read.
+func (m *MultiReader) Close() error {
+	return m.closeFile()
+}
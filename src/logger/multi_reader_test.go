@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"env"
+)
+
+type position struct {
+	logNumber uint64
+	offset    int64
+}
+
+// writeLogs writes a log for each of records to the directory "db" of a new MemFS, each record being
+// written as a FULL record of its own.
+func writeLogs(t *testing.T, records ...[][]byte) *env.MemFS {
+	fs := env.NewMemFS()
+	m, err := OpenLogManager(fs, "db", LogManagerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, log := range records {
+		if _, err := m.Roll(); err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range log {
+			if _, _, err := m.Write(record, WriteOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+// truncateFile cuts the file name off after size bytes.
+func truncateFile(t *testing.T, fs env.FS, name string, size int) {
+	f, err := fs.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs.Remove(name)
+	if f, err = fs.Create(name); err != nil {
+		t.Fatal(err)
+	}
+	f.Write(data[:size])
+	f.Close()
+}
+
+func readAllLogs(t *testing.T, mr *MultiReader) ([]string, []position) {
+	defer mr.Close()
+	var records []string
+	var positions []position
+	for mr.Next() {
+		records = append(records, string(mr.Record()))
+		positions = append(positions, position{mr.LogNumber(), mr.Offset()})
+	}
+	return records, positions
+}
+
+func TestMultiReader_ShouldReadTheLogsInOrder(t *testing.T) {
+	fs := writeLogs(t,
+		[][]byte{[]byte("a"), []byte("b")},
+		[][]byte{},
+		[][]byte{[]byte("c")},
+	)
+
+	mr, err := NewMultiReader(fs, "db", ReaderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, positions := readAllLogs(t, mr)
+	if mr.Err() != nil {
+		t.Fatal(mr.Err())
+	}
+	if expected := []string{"a", "b", "c"}; !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected %v but got %v", expected, records)
+	}
+	expected := []position{{1, 0}, {1, recordHeaderSize + 1}, {3, 0}}
+	if !reflect.DeepEqual(positions, expected) {
+		t.Errorf("Expected %v but got %v", expected, positions)
+	}
+}
+
+func TestMultiReader_TruncatedLog(t *testing.T) {
+	tests := map[string]struct {
+		truncatedLog     uint64
+		reporter         *recordingReporter
+		expectedRecords  []string
+		expectedError    error
+		expectedReported []int
+	}{
+		"Last log should end cleanly": {
+			truncatedLog:    2,
+			expectedRecords: []string{"a", "bb", "c"},
+		},
+		"Last log should end cleanly in recovery mode": {
+			truncatedLog:    2,
+			reporter:        new(recordingReporter),
+			expectedRecords: []string{"a", "bb", "c"},
+		},
+		"Earlier log should be an error": {
+			truncatedLog:    1,
+			expectedRecords: []string{"a"},
+			expectedError:   TruncatedLogError{1},
+		},
+		"Earlier log should be reported in recovery mode": {
+			truncatedLog:     1,
+			reporter:         new(recordingReporter),
+			expectedRecords:  []string{"a", "c", "dd"},
+			expectedReported: []int{recordHeaderSize + 1},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			fs := writeLogs(t,
+				[][]byte{[]byte("a"), []byte("bb")},
+				[][]byte{[]byte("c"), []byte("dd")},
+			)
+			// Cuts off the last byte of the second record.
+			truncateFile(t, fs, LogFileName("db", test.truncatedLog), 2*recordHeaderSize+2)
+
+			opts := ReaderOptions{}
+			if test.reporter != nil {
+				opts.Reporter = test.reporter
+			}
+			mr, err := NewMultiReader(fs, "db", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			records, _ := readAllLogs(t, mr)
+			if !reflect.DeepEqual(records, test.expectedRecords) {
+				t.Errorf("Expected %v but got %v", test.expectedRecords, records)
+			}
+			if mr.Err() != test.expectedError {
+				t.Errorf("Expected '%v' but got '%v'", test.expectedError, mr.Err())
+			}
+			if test.reporter != nil && !reflect.DeepEqual(test.reporter.droppedBytes, test.expectedReported) {
+				t.Errorf("Expected %v to be reported but got %v", test.expectedReported, test.reporter.droppedBytes)
+			}
+		})
+	}
+}
+
+func TestMultiReader_TruncatedLogShouldBeReportedWithItsOffset(t *testing.T) {
+	fs := writeLogs(t,
+		[][]byte{[]byte("a"), []byte("bb")},
+		[][]byte{[]byte("c")},
+	)
+	truncateFile(t, fs, LogFileName("db", 1), 2*recordHeaderSize+2)
+
+	reporter := new(offsetRecordingReporter)
+	mr, err := NewMultiReader(fs, "db", ReaderOptions{Reporter: reporter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAllLogs(t, mr)
+	// The second record starts after the first one and its header.
+	if expected := []int64{recordHeaderSize + 1}; !reflect.DeepEqual(reporter.offsets, expected) {
+		t.Errorf("Expected the corruption to be reported at %v but got %v", expected, reporter.offsets)
+	}
+	if expected := []int{recordHeaderSize + 1}; !reflect.DeepEqual(reporter.droppedBytes, expected) {
+		t.Errorf("Expected %v to be reported but got %v", expected, reporter.droppedBytes)
+	}
+}
@@ -1,132 +1,690 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
 	"hash"
 	"hash/crc32"
 	"io"
+
+	"crc32c"
 )
 
 type RecordReader struct {
-	src *trackingReader
+	src  io.ReadSeeker
+	opts ReaderOptions
+
+	hash       hash.Hash32
+	legacyHash hash.Hash32
+
+	// block holds the current block, of which filled bytes have been read. buf is the part of those
+	// that is yet to be decoded.
+	block  []byte
+	filled int
+	buf    []byte
+	// eof is set once a read from src returned less than a full block. Only a follow mode reader
+	// clears it again, to read what has been written since.
+	eof    bool
+	follow bool
+	// bufEnd is the offset in src just past the last byte of buf.
+	bufEnd int64
+
+	// logNumber is the log number of the recyclable records to return, recycled is set once one was read.
+	logNumber uint32
+	recycled  bool
+
+	// fragmentOffset is the offset in src of the fragment last returned by readFragment.
+	fragmentOffset int64
+	// initialOffset is where the reader was asked to start. Fragments before it are skipped and so are
+	// the MIDDLE and LAST fragments right after it while resyncing is set.
+	initialOffset int64
+	resyncing     bool
+	// skipped counts the fragments of unknown types skipped with SkipUnknownTypes.
+	skipped int64
+	// truncated is the number of bytes of the last record, starting at truncatedOffset, that were
+	// dropped in recovery mode because the log ended within it.
+	truncated       int
+	truncatedOffset int64
+
+	// prevRecordType, recordOffset, recordCompressed and scratch hold the record being reassembled by
+	// readRecord.
+	prevRecordType   recordType
+	recordOffset     int64
+	recordCompressed bool
+	scratch          []byte
+
+	decompressor decompressor
+	stats        CompressionStats
+	cipher       *recordCipher
+
+	record []byte
+	offset int64
+	err    error
+}
 
-	header header
+// ReaderOptions control how a RecordReader decodes the log.
+type ReaderOptions struct {
+	// LegacyChecksum makes the reader also accept fragments whose checksum is the unmasked IEEE crc32
+	// written by earlier versions of this package, so that existing log files remain readable.
+	LegacyChecksum bool
 
-	hash hash.Hash32
-	buf  []byte
+	// Reporter puts the reader in recovery mode when set. Instead of failing on a damaged fragment the
+	// reader reports the dropped bytes, resyncs at the next block boundary and carries on with the next
+	// intact record. A record cut short by the end of the log is dropped without being reported, as
+	// that is what a writer that died mid-record leaves behind.
+	Reporter Reporter
+
+	// LogNumber is the number of the log to read from a reused file. A recyclable record with any other
+	// log number was left over from an earlier use of the file and marks the end of the log. When it is
+	// zero, the reader takes the log number from the first recyclable record it reads.
+	//
+	// In a log of recyclable records a damaged fragment also marks the end of the log, as it cannot be
+	// told apart from a partly overwritten record of the earlier use.
+	LogNumber uint32
+
+	// SkipUnknownTypes makes the reader skip fragments of record types it does not understand, as
+	// the format allows, instead of treating them as corruption. SkippedRecords counts them.
+	SkipUnknownTypes bool
+
+	// Metadata is called with the metadata records written by RecordWriter.WriteMetadata. The record
+	// is only valid during the call. Metadata records are skipped when it is nil.
+	Metadata func(t MetadataType, record []byte)
+
+	// Keys is where the reader gets the key of an encrypted log from, using the key ID in the PREAMBLE
+	// record at its start. The PREAMBLE record is read first even when the reader starts further in.
+	// Without Keys reading an encrypted log is an error, and so is reading a log that is not encrypted
+	// with them.
+	Keys KeyProvider
 }
 
+// Reporter is told about the corruption a RecordReader skips over in recovery mode.
+type Reporter interface {
+	// Corruption is called with the approximate number of bytes dropped and the reason for dropping them.
+	Corruption(bytes int, reason error)
+}
+
+// OffsetReporter is a Reporter that is also told where the dropped bytes start in the log. A
+// RecordReader or MultiReader calls CorruptionAt instead of Corruption when its Reporter is one.
+type OffsetReporter interface {
+	Reporter
+	CorruptionAt(offset int64, bytes int, reason error)
+}
+
+// NewRecordReader creates a reader with the default ReaderOptions.
 func NewRecordReader(src io.ReadSeeker, srcLength int64) *RecordReader {
-	src.Seek(srcLength, io.SeekStart)
+	return NewRecordReaderWithOptions(src, srcLength, ReaderOptions{})
+}
+
+// NewRecordReaderWithOptions creates a reader that returns the records of src that start at or after
+// initialOffset.
+//
+// The offset does not have to be a record boundary. The reader seeks to the start of the block that
+// contains initialOffset and skips the fragments before it, along with the MIDDLE and LAST fragments of
+// a record that started before it.
+func NewRecordReaderWithOptions(src io.ReadSeeker, initialOffset int64, opts ReaderOptions) *RecordReader {
+	blockStart := initialOffset - initialOffset%blockSize
+	if initialOffset%blockSize > blockSize-(recordHeaderSize-1) {
+		// A record cannot start in the trailer.
+		blockStart += blockSize
+	}
+
+	src.Seek(blockStart, io.SeekStart)
+	block := make([]byte, blockSize)
 	return &RecordReader{
-		src:    newTrackingReader(src, srcLength),
-		header: newHeader(),
-		hash:   crc32.NewIEEE(),
-		buf:    make([]byte, blockSize-recordHeaderSize)}
+		src:           src,
+		opts:          opts,
+		logNumber:     opts.LogNumber,
+		hash:          crc32c.New(),
+		legacyHash:    crc32.NewIEEE(),
+		block:         block,
+		filled:        blockSize,
+		buf:           block[:0],
+		bufEnd:        blockStart,
+		initialOffset: initialOffset,
+		resyncing:     initialOffset > 0,
+	}
 }
 
 var errorHeaderEOF = fmt.Errorf("could not read record header: %v", io.EOF)
 var errorBodyEOF = fmt.Errorf("count not read record body: %v", io.EOF)
+var errorBadRecordLength = errors.New("bad record length")
 
 // Read reads from reader decodes record header, validates checksum and writes to the writer.
+//
+// It returns the number of bytes written and io.EOF once there are no more records. Without a Reporter
+// any damaged fragment is an error and the fragments read so far are already written to w. In recovery
+// mode only whole records are written to w.
 func (rr *RecordReader) Read(w io.Writer) (int, error) {
-	if err := rr.src.SkipEndOfBlock(); err != nil {
+	if rr.opts.Reporter == nil {
+		return rr.readStrict(w)
+	}
+
+	record, _, err := rr.readRecord()
+	if err != nil {
 		return 0, err
 	}
+	n, err := w.Write(record)
+	if err != nil {
+		return n, fmt.Errorf("cannot write to buffer: %v", err)
+	}
+	return n, nil
+}
 
+func (rr *RecordReader) readStrict(w io.Writer) (int, error) {
 	hasMore := true
 	prevRecordType := uninit
 	var totalBytesWritten int
 
+	compressed := false
+	var recordOffset int64
+	rr.scratch = rr.scratch[:0]
+
 	for hasMore {
-		if _, err := rr.src.ReadFull(rr.header); err != nil {
-			return totalBytesWritten, errorHeaderEOF
+		h, body, err := rr.nextFragment()
+		if err == io.EOF && prevRecordType != uninit {
+			err = errorHeaderEOF
 		}
-
-		buf := rr.buf[0:rr.header.Length()]
-		if _, err := rr.src.ReadFull(buf); err != nil {
-			return totalBytesWritten, errorBodyEOF
+		if c, ok := err.(corruption); ok {
+			err = c.reason
 		}
-
-		rr.hash.Reset()
-		rr.hash.Write(rr.header.RecordTypeByte())
-		rr.hash.Write(buf)
-		if checksum := rr.hash.Sum32(); rr.header.Checksum() != checksum {
-			return totalBytesWritten, fmt.Errorf("failed checksum for record fragment: %d != %d", rr.header.Checksum(), checksum)
+		if err != nil {
+			return totalBytesWritten, err
 		}
 
-		expectMore, err := shouldExpectMoreRecordFragments(prevRecordType, rr.header.RecordType())
+		expectMore, err := shouldExpectMoreRecordFragments(prevRecordType, h.RecordType())
 		if err != nil {
 			return totalBytesWritten, err
 		}
+		if prevRecordType == uninit {
+			recordOffset = rr.fragmentOffset
+		}
 		hasMore = expectMore
-		prevRecordType = rr.header.RecordType()
+		prevRecordType = h.RecordType()
 
-		count, err := w.Write(buf)
+		// A compressed or encrypted record can only be written once all of it has been read.
+		if compressed = compressed || h.RecordType().isCompressed(); compressed || rr.cipher != nil {
+			rr.scratch = append(rr.scratch, body...)
+			continue
+		}
+		count, err := w.Write(body)
 		totalBytesWritten += count
 		if err != nil {
 			return totalBytesWritten, fmt.Errorf("cannot write to buffer: %v", err)
 		}
 	}
 
-	return 0, nil
+	if !compressed && rr.cipher == nil {
+		rr.stats.add(totalBytesWritten, totalBytesWritten)
+		return totalBytesWritten, nil
+	}
+	record, err := rr.decode(rr.scratch, recordOffset, compressed)
+	if err != nil {
+		return 0, err
+	}
+	count, err := w.Write(record)
+	if err != nil {
+		return count, fmt.Errorf("cannot write to buffer: %v", err)
+	}
+	return count, nil
+}
+
+// Next advances to the next record, which is then available through Record and Offset.
+// It returns false at the end of the log or when an error stops the reader, see Err.
+func (rr *RecordReader) Next() bool {
+	if rr.err != nil {
+		return false
+	}
+	rr.record, rr.offset, rr.err = rr.readRecord()
+	return rr.err == nil
 }
 
-func shouldExpectMoreRecordFragments(prev, curr recordType) (bool, error) {
-	if prev == uninit && curr == FULL {
-		return false, nil
+// Record returns the current record. A record stored as a single FULL fragment is not copied, so the
+// slice is only valid until the next call to Next or Read.
+func (rr *RecordReader) Record() []byte {
+	return rr.record
+}
+
+// Offset returns the offset in src of the first fragment of the current record.
+func (rr *RecordReader) Offset() int64 {
+	return rr.offset
+}
+
+// Truncated returns the offset and length of the incomplete record that the log ended within, which
+// Next drops in recovery mode without reporting it as corruption. The length is zero when the log ended
+// at a record boundary, or before Next returned false.
+func (rr *RecordReader) Truncated() (int64, int) {
+	return rr.truncatedOffset, rr.truncated
+}
+
+// CompressionStats returns the sizes of the user records read so far before and after compression.
+func (rr *RecordReader) CompressionStats() CompressionStats {
+	return rr.stats
+}
+
+// SkippedRecords returns the number of fragments of unknown record types skipped so far.
+func (rr *RecordReader) SkippedRecords() int64 {
+	return rr.skipped
+}
+
+// Err returns the error that stopped Next. It is nil when Next stopped at the end of the log.
+func (rr *RecordReader) Err() error {
+	if rr.err == io.EOF {
+		return nil
 	}
-	if prev == uninit && curr == FIRST {
-		return true, nil
+	return rr.err
+}
+
+// readRecord returns the next record and the offset of its first fragment. Fragmented records are
+// reassembled in rr.scratch, FULL records are returned straight from the block buffer.
+//
+// Without a Reporter any damaged or out of place fragment is an error. In recovery mode it is reported
+// and skipped, and a record cut short by the end of the log is treated as the end of the log. A follow
+// mode reader returns ErrCaughtUp instead and keeps the fragments read so far for the next call.
+func (rr *RecordReader) readRecord() ([]byte, int64, error) {
+	for {
+		h, body, err := rr.nextFragment()
+		switch e := err.(type) {
+		case nil:
+		case corruption:
+			if rr.prevRecordType != uninit {
+				e.offset, e.bytes = rr.recordOffset, e.bytes+len(rr.scratch)
+			}
+			rr.resetRecord()
+			if err := rr.corrupt(e.offset, e.bytes, e.reason); err != nil {
+				return nil, 0, err
+			}
+			continue
+		default:
+			if err == ErrCaughtUp {
+				return nil, 0, err
+			}
+			if rr.opts.Reporter != nil && (err == errorHeaderEOF || err == errorBodyEOF || err == io.EOF && rr.prevRecordType != uninit) {
+				rr.truncatedOffset, rr.truncated = rr.bufEnd-int64(len(rr.buf)), len(rr.scratch)+len(rr.buf)
+				if rr.prevRecordType != uninit {
+					rr.truncatedOffset = rr.recordOffset
+				}
+				err = io.EOF
+			} else if rr.opts.Reporter == nil && err == io.EOF && rr.prevRecordType != uninit {
+				err = errorHeaderEOF
+			}
+			rr.resetRecord()
+			return nil, 0, err
+		}
+
+		switch rt := h.RecordType(); rt.base() {
+		case FULL, FIRST:
+			if rr.prevRecordType != uninit {
+				prev, dropped := rr.prevRecordType, len(rr.scratch)
+				rr.resetRecord()
+				if err := rr.corrupt(rr.recordOffset, dropped, RecordTypeMissmatchError{prev, rt}); err != nil {
+					return nil, 0, err
+				}
+			}
+			rr.recordOffset = rr.fragmentOffset
+			rr.recordCompressed = rt.isCompressed()
+			if rt.base() == FULL {
+				record, err := rr.decode(body, rr.recordOffset, rr.recordCompressed)
+				if err != nil {
+					if err := rr.corrupt(rr.recordOffset, len(body), err); err != nil {
+						return nil, 0, err
+					}
+					continue
+				}
+				return record, rr.recordOffset, nil
+			}
+			rr.scratch = append(rr.scratch[:0], body...)
+			rr.prevRecordType = rt
+		case MIDDLE, LAST:
+			if rr.prevRecordType == uninit || rr.prevRecordType.isRecyclable() != rt.isRecyclable() {
+				prev, offset, dropped := rr.prevRecordType, rr.dropOffset(), len(rr.scratch)+len(body)
+				rr.resetRecord()
+				if err := rr.corrupt(offset, dropped, RecordTypeMissmatchError{prev, rt}); err != nil {
+					return nil, 0, err
+				}
+				continue
+			}
+			rr.scratch = append(rr.scratch, body...)
+			if rt.base() == LAST {
+				payload := rr.scratch
+				rr.resetRecord()
+				record, err := rr.decode(payload, rr.recordOffset, rr.recordCompressed)
+				if err != nil {
+					if err := rr.corrupt(rr.recordOffset, len(payload), err); err != nil {
+						return nil, 0, err
+					}
+					continue
+				}
+				return record, rr.recordOffset, nil
+			}
+			rr.prevRecordType = rt
+		default:
+			prev, offset, dropped := rr.prevRecordType, rr.dropOffset(), recordHeaderSize+len(body)+len(rr.scratch)
+			rr.resetRecord()
+			if err := rr.corrupt(offset, dropped, RecordTypeMissmatchError{prev, rt}); err != nil {
+				return nil, 0, err
+			}
+		}
 	}
-	if prev == FIRST && curr == MIDDLE {
-		return true, nil
+}
+
+// decode returns the user record stored as payload at offset and counts it in the CompressionStats.
+func (rr *RecordReader) decode(payload []byte, offset int64, compressed bool) ([]byte, error) {
+	if rr.cipher != nil {
+		var err error
+		if payload, err = rr.cipher.open(payload, offset); err != nil {
+			return nil, err
+		}
 	}
-	if (prev == FIRST || prev == MIDDLE) && curr == LAST {
-		return false, nil
+	if !compressed {
+		rr.stats.add(len(payload), len(payload))
+		return payload, nil
 	}
+	record, err := rr.decompressor.decompress(payload)
+	if err != nil {
+		return nil, err
+	}
+	rr.stats.add(len(record), len(payload))
+	return record, nil
+}
 
-	return false, RecordTypeMissmatchError{prev, curr}
+// resetRecord forgets the fragments of the record being reassembled. rr.scratch keeps its contents
+// until the next fragment is appended, so a record returned from it stays valid.
+func (rr *RecordReader) resetRecord() {
+	rr.scratch = rr.scratch[:0]
+	rr.prevRecordType = uninit
 }
 
-type RecordTypeMissmatchError struct {
-	prev, curr recordType
+// dropOffset returns the offset of the record being reassembled or, when there is none, of the fragment
+// last read. It is where the bytes dropped along with that fragment start.
+func (rr *RecordReader) dropOffset() int64 {
+	if rr.prevRecordType != uninit {
+		return rr.recordOffset
+	}
+	return rr.fragmentOffset
 }
 
-func (r RecordTypeMissmatchError) Error() string {
-	return fmt.Sprintf("unexpected recordType, %v => %v not allowed", r.prev, r.curr)
+// corrupt reports bytes dropped from offset on because of reason in recovery mode. Otherwise reason is
+// returned as an error, and so is ErrTampered in any mode.
+func (rr *RecordReader) corrupt(offset int64, bytes int, reason error) error {
+	if rr.opts.Reporter == nil || reason == ErrTampered {
+		return reason
+	}
+	if r, ok := rr.opts.Reporter.(OffsetReporter); ok && bytes > 0 {
+		r.CorruptionAt(offset, bytes, reason)
+	} else if bytes > 0 {
+		rr.opts.Reporter.Corruption(bytes, reason)
+	}
+	return nil
 }
 
-const skippableEndOfBlockSize = uint32(recordHeaderSize - 1)
+// nextFragment is readFragment without the MIDDLE and LAST fragments of a record that started before
+// the initial offset, metadata records, the PREAMBLE record and, with SkipUnknownTypes, fragments of
+// unknown types.
+func (rr *RecordReader) nextFragment() (header, []byte, error) {
+	if rr.opts.Keys != nil && rr.cipher == nil {
+		if err := rr.readPreamble(); err != nil {
+			return nil, nil, err
+		}
+	}
+	for {
+		h, body, err := rr.readFragment()
+		if err != nil {
+			return h, body, err
+		}
+		if rt := h.RecordType(); rt == PREAMBLE {
+			if rr.opts.Keys == nil {
+				return nil, nil, errorEncryptedLog
+			}
+			continue
+		} else if rt.isMetadata() {
+			if rr.opts.Metadata != nil {
+				rr.opts.Metadata(rt.metadataType(), body)
+			}
+			continue
+		} else if !rt.isKnown() && rr.opts.SkipUnknownTypes {
+			rr.skipped++
+			continue
+		}
+		if !rr.resyncing {
+			return h, body, nil
+		}
+		switch h.RecordType().base() {
+		case MIDDLE:
+		case LAST:
+			rr.resyncing = false
+		default:
+			rr.resyncing = false
+			return h, body, nil
+		}
+	}
+}
 
-type trackingReader struct {
-	io.Reader
-	blockOffset uint32
+// readPreamble sets up the cipher from the PREAMBLE record at the start of src, and then seeks back to
+// where the reader is.
+//
+// A log that is too short to hold the PREAMBLE record ends before its first record, which is io.EOF or,
+// when it ends within the PREAMBLE record, errorHeaderEOF. In follow mode both are ErrCaughtUp.
+func (rr *RecordReader) readPreamble() error {
+	buf := make([]byte, recordHeaderSize+preambleSize)
+	if _, err := rr.src.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("could not seek to 0: %v", err)
+	}
+	n, err := io.ReadFull(rr.src, buf)
+	if _, err := rr.src.Seek(rr.bufEnd, io.SeekStart); err != nil {
+		return fmt.Errorf("could not seek to %d: %v", rr.bufEnd, err)
+	}
+	h, body := header(buf[:recordHeaderSize]), buf[recordHeaderSize:]
+	switch {
+	case err != nil && err != io.EOF && err != io.ErrUnexpectedEOF:
+		return fmt.Errorf("could not read preamble: %v", err)
+	case n >= recordHeaderSize && (h.RecordType() != PREAMBLE || int(h.Length()) != preambleSize):
+		return errorMissingPreamble
+	case err != nil && rr.follow:
+		return ErrCaughtUp
+	case n == 0:
+		return io.EOF
+	case err != nil:
+		return errorHeaderEOF
+	}
 
-	skippableEndOfBlockBuf []byte
+	if err := rr.verifyChecksum(h, body); err != nil {
+		return errorBadPreamble
+	}
+	rr.cipher, err = newReaderCipher(rr.opts.Keys, body)
+	return err
 }
 
-func newTrackingReader(src io.ReadSeeker, seekOffset int64) *trackingReader {
-	return &trackingReader{
-		Reader:                 src,
-		blockOffset:            uint32(seekOffset % blockSize),
-		skippableEndOfBlockBuf: make([]byte, skippableEndOfBlockSize),
+// corruption is returned by readFragment when bytes of the log had to be dropped because of reason.
+type corruption struct {
+	offset int64
+	bytes  int
+	reason error
+}
+
+func (c corruption) Error() string {
+	return fmt.Sprintf("dropped %d bytes: %v", c.bytes, c.reason)
+}
+
+// readFragment returns the header and body of the next fragment. Both alias the block buffer.
+//
+// It returns io.EOF at the end of src and errorHeaderEOF or errorBodyEOF when src ends within a fragment.
+// In follow mode all three are ErrCaughtUp and the partial fragment is kept, to be completed by the
+// next read. A damaged fragment is returned as a corruption and the rest of its block is dropped with
+// it, since its length cannot be trusted. In a recycled log it is the end of the log instead, and so is
+// a fragment of another log.
+func (rr *RecordReader) readFragment() (header, []byte, error) {
+	for {
+		if rr.recycled && rr.filled == blockSize && len(rr.buf) < recyclableRecordHeaderSize {
+			// No recyclable header fits, so the rest of the block is the trailer the writer filled with
+			// zeros. Its first 7 bytes would otherwise read as a zero header, the end of the log.
+			rr.buf = rr.buf[:0]
+		}
+		headerSize := headerSizeOf(rr.buf)
+		if len(rr.buf) < headerSize {
+			if !rr.eof {
+				// Whatever is left is the trailer of the block, or the start of a header in follow mode.
+				if err := rr.readBlock(); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			if rr.follow {
+				return nil, nil, ErrCaughtUp
+			}
+			if len(rr.buf) == 0 {
+				return nil, nil, io.EOF
+			}
+			return nil, nil, errorHeaderEOF
+		}
+
+		h := header(rr.buf[:headerSize])
+		length := int(h.Length())
+		if headerSize+length > len(rr.buf) {
+			switch {
+			case !rr.eof && rr.filled < blockSize:
+				if err := rr.readBlock(); err != nil {
+					return nil, nil, err
+				}
+				continue
+			case rr.eof && rr.follow:
+				return nil, nil, ErrCaughtUp
+			case rr.eof:
+				return nil, nil, errorBodyEOF
+			case rr.recycled:
+				return nil, nil, rr.endOfRecycledLog()
+			}
+			return nil, nil, rr.dropBuffer(errorBadRecordLength)
+		}
+
+		if h.RecordType() == uninit && length == 0 {
+			// Zero filled space, as found at the end of a preallocated file.
+			if rr.recycled {
+				return nil, nil, rr.endOfRecycledLog()
+			}
+			rr.buf = rr.buf[:0]
+			continue
+		}
+
+		body := rr.buf[headerSize : headerSize+length]
+		if err := rr.verifyChecksum(h, body); err != nil {
+			if rr.recycled {
+				return nil, nil, rr.endOfRecycledLog()
+			}
+			return nil, nil, rr.dropBuffer(err)
+		}
+		if h.RecordType().isRecyclable() {
+			if rr.logNumber == 0 {
+				rr.logNumber = h.LogNumber()
+			}
+			rr.recycled = true
+			if h.LogNumber() != rr.logNumber {
+				return nil, nil, rr.endOfRecycledLog()
+			}
+		}
+		rr.fragmentOffset = rr.bufEnd - int64(len(rr.buf))
+		rr.buf = rr.buf[headerSize+length:]
+		if rr.fragmentOffset < rr.initialOffset {
+			continue
+		}
+		return h, body, nil
 	}
 }
 
-func (r *trackingReader) SkipEndOfBlock() error {
-	if blockSize-r.blockOffset > skippableEndOfBlockSize {
+// readBlock reads up to the end of the block. Once the block is complete the next read starts a new
+// one, dropping the trailer left in buf. A block cut short by the end of src is only read into again
+// in follow mode, appending to the unread bytes in buf.
+func (rr *RecordReader) readBlock() error {
+	start := rr.filled - len(rr.buf)
+	if rr.filled == blockSize {
+		start, rr.filled = 0, 0
+	}
+
+	n, err := io.ReadFull(rr.src, rr.block[rr.filled:])
+	rr.filled += n
+	rr.buf = rr.block[start:rr.filled]
+	rr.bufEnd += int64(n)
+	switch err {
+	case nil:
+		return nil
+	case io.EOF, io.ErrUnexpectedEOF:
+		rr.eof = true
 		return nil
+	default:
+		return fmt.Errorf("could not read block: %v", err)
 	}
+}
 
-	if _, err := r.ReadFull(r.skippableEndOfBlockBuf); err != nil {
-		return err
+// endOfRecycledLog stops the reader at the start of buf, where the data of an earlier use of the file
+// begins. In follow mode that data is yet to be overwritten, so it is read again on the next attempt.
+func (rr *RecordReader) endOfRecycledLog() error {
+	rr.eof = true
+	if !rr.follow {
+		rr.buf = rr.buf[:0]
+		return io.EOF
 	}
-	return nil
+
+	rr.bufEnd -= int64(len(rr.buf))
+	rr.filled -= len(rr.buf)
+	rr.buf = rr.buf[:0]
+	if _, err := rr.src.Seek(rr.bufEnd, io.SeekStart); err != nil {
+		return fmt.Errorf("could not seek to %d: %v", rr.bufEnd, err)
+	}
+	return ErrCaughtUp
+}
+
+func (rr *RecordReader) dropBuffer(reason error) error {
+	c := corruption{offset: rr.bufEnd - int64(len(rr.buf)), bytes: len(rr.buf), reason: reason}
+	rr.buf = rr.buf[:0]
+	return c
+}
+
+// verifyChecksum checks the masked crc32c in h against the fragment body.
+// In LegacyChecksum mode an unmasked IEEE crc32 is accepted as well.
+func (rr *RecordReader) verifyChecksum(h header, body []byte) error {
+	checksum := crc32c.Mask(fragmentChecksum(rr.hash, h, body))
+	if h.Checksum() == checksum {
+		return nil
+	}
+	if rr.opts.LegacyChecksum && h.Checksum() == fragmentChecksum(rr.legacyHash, h, body) {
+		return nil
+	}
+	return checksumError(h.Checksum(), checksum)
+}
+
+func checksumError(stored, computed uint32) error {
+	return fmt.Errorf("failed checksum for record fragment: %d != %d", stored, computed)
+}
+
+func fragmentChecksum(h hash.Hash32, hdr header, body []byte) uint32 {
+	h.Reset()
+	h.Write(hdr.ChecksummedBytes())
+	h.Write(body)
+	return h.Sum32()
+}
+
+func shouldExpectMoreRecordFragments(prev, curr recordType) (bool, error) {
+	if !curr.isKnown() || prev != uninit && (curr.isCompressed() || prev.isRecyclable() != curr.isRecyclable()) {
+		return false, RecordTypeMissmatchError{prev, curr}
+	}
+
+	switch p, c := prev.base(), curr.base(); {
+	case p == uninit && c == FULL:
+		return false, nil
+	case p == uninit && c == FIRST:
+		return true, nil
+	case (p == FIRST || p == MIDDLE) && c == MIDDLE:
+		return true, nil
+	case (p == FIRST || p == MIDDLE) && c == LAST:
+		return false, nil
+	}
+
+	return false, RecordTypeMissmatchError{prev, curr}
+}
+
+type RecordTypeMissmatchError struct {
+	prev, curr recordType
 }
 
-func (r *trackingReader) ReadFull(buf []byte) (int, error) {
-	n, err := io.ReadFull(r, buf)
-	r.blockOffset += uint32(n)
-	return n, err
+func (r RecordTypeMissmatchError) Error() string {
+	return fmt.Sprintf("unexpected recordType, %v => %v not allowed", r.prev, r.curr)
 }
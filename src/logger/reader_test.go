@@ -3,13 +3,18 @@ package logger
 import (
 	"bytes"
 	"fmt"
+	"hash/crc32"
+	"io"
 	"reflect"
 	"testing"
+	"time"
+
+	"env"
 )
 
 func TestRecordReader_Success(t *testing.T) {
 	tests := map[string]struct {
-		fileOffset int64
+		fileOffset int
 	}{
 		"Read full record": {
 			fileOffset: 0,
@@ -24,20 +29,19 @@ func TestRecordReader_Success(t *testing.T) {
 
 	for testName, test := range tests {
 		t.Run(testName, func(t *testing.T) {
-			buf := new(OnlyOnceSeekableBuffer)
-			w := NewRecordWriter(buf, test.fileOffset)
+			fs, f := newTestLog(t)
+			w := newRecordWriterAt(t, f, test.fileOffset)
 			input := []byte("hello world")
 
 			writeFailOnError(t, w, input)
-			buf.ResetSeeker()
-			readRecordAndVerify(t, NewRecordReader(buf, test.fileOffset), input)
+			readRecordAndVerify(t, NewRecordReader(openTestLog(t, fs), int64(test.fileOffset)), input)
 		})
 	}
 }
 
 func TestMultipleRecords(t *testing.T) {
-	buf := new(OnlyOnceSeekableBuffer)
-	w := NewRecordWriter(buf, 0)
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
 
 	const bs = blockSize - recordHeaderSize
 	input := make([]byte, 3*bs)
@@ -46,23 +50,22 @@ func TestMultipleRecords(t *testing.T) {
 	fill(input[2*bs:3*bs], 51)
 
 	writeFailOnError(t, w, input)
-	buf.ResetSeeker()
-	readRecordAndVerify(t, NewRecordReader(buf, 0), input)
+	readRecordAndVerify(t, NewRecordReader(openTestLog(t, fs), 0), input)
 }
 
 func TestRecordRead_Error(t *testing.T) {
 	tests := map[string]struct {
-		buf           *DiscardAfterBuffer
+		size          int
 		input         string
 		expectedError error
 	}{
 		"First fragment successful but dies before writing the second record body; should return errorBodyEOF": {
-			buf:           &DiscardAfterBuffer{N: 14},
+			size:          blockSize - recordHeaderSize + 14,
 			input:         "hello",
 			expectedError: errorBodyEOF,
 		},
 		"First fragment successful but dies before writing the second record header; should return errorHeaderEOF": {
-			buf:           &DiscardAfterBuffer{N: 13},
+			size:          blockSize - recordHeaderSize + 13,
 			input:         "hello",
 			expectedError: errorHeaderEOF,
 		},
@@ -70,11 +73,12 @@ func TestRecordRead_Error(t *testing.T) {
 
 	for testName, test := range tests {
 		t.Run(testName, func(t *testing.T) {
-			writeFailOnError(t, NewRecordWriter(test.buf, blockSize-recordHeaderSize), []byte(test.input))
+			fs, f := newTestLog(t)
+			writeFailOnError(t, newRecordWriterAt(t, f, blockSize-recordHeaderSize), []byte(test.input))
+			truncateTestLog(t, fs, test.size)
 
 			resultBuf := new(bytes.Buffer)
-			test.buf.ResetSeeker()
-			_, err := NewRecordReader(test.buf, blockSize-(recordHeaderSize)).Read(resultBuf)
+			_, err := NewRecordReader(openTestLog(t, fs), blockSize-(recordHeaderSize)).Read(resultBuf)
 			if err != test.expectedError {
 				t.Fatalf("Expected '%v' but got '%v'", test.expectedError, err)
 			}
@@ -86,19 +90,10 @@ func TestRecordRead_Error(t *testing.T) {
 // first record fragment and the writer sucessfully writes the second record as a full fragment,
 // reading the records should fail with a RecordTypeMissmatchError.
 func TestRecordRead_MultipleRecord_FirstPartialRecordSecondFullRecord_ShouldBeAnError(t *testing.T) {
-	buf := &DiscardAfterBuffer{N: 8}
-	rw := NewRecordWriter(buf, blockSize-recordHeaderSize-1)
-
-	firstMultiFragmentRecord := "first"
-	writeFailOnError(t, rw, []byte(firstMultiFragmentRecord))
-
-	secondFullRecord := "second"
-	buf.N = recordHeaderSize + len(secondFullRecord)
-	writeFailOnError(t, rw, []byte(secondFullRecord))
+	fs := writeLogWithLostLastFragment(t)
 
-	buf.ResetSeeker()
 	resultBuf := new(bytes.Buffer)
-	n, err := NewRecordReader(buf, blockSize-recordHeaderSize-1).Read(resultBuf)
+	n, err := NewRecordReader(openTestLog(t, fs), blockSize-recordHeaderSize-1).Read(resultBuf)
 
 	expectedErr := RecordTypeMissmatchError{FIRST, FULL}
 	if err != expectedErr || n != 1 {
@@ -130,6 +125,11 @@ func TestShouldExpectMoreRecordFragment(t *testing.T) {
 			current:                  MIDDLE,
 			expectedShouldExpectMore: true,
 		},
+		{
+			prev:                     MIDDLE,
+			current:                  MIDDLE,
+			expectedShouldExpectMore: true,
+		},
 		{
 			prev:                     FIRST,
 			current:                  LAST,
@@ -140,6 +140,21 @@ func TestShouldExpectMoreRecordFragment(t *testing.T) {
 			current:                  LAST,
 			expectedShouldExpectMore: false,
 		},
+		{
+			prev:                     uninit,
+			current:                  RecyclableFULL,
+			expectedShouldExpectMore: false,
+		},
+		{
+			prev:                     RecyclableFIRST,
+			current:                  RecyclableMIDDLE,
+			expectedShouldExpectMore: true,
+		},
+		{
+			prev:                     RecyclableMIDDLE,
+			current:                  RecyclableLAST,
+			expectedShouldExpectMore: false,
+		},
 	}
 	for _, testData := range tests {
 		testName := fmt.Sprintf("%v => %v should be valid", testData.prev, testData.current)
@@ -159,6 +174,9 @@ func TestShouldExpectMoreRecordFragment_Error(t *testing.T) {
 		current recordType
 	}{
 		{prev: uninit, current: LAST},
+		{prev: uninit, current: RecyclableLAST},
+		{prev: FIRST, current: RecyclableLAST},
+		{prev: RecyclableFIRST, current: MIDDLE},
 	}
 	for _, testData := range tests {
 		testName := fmt.Sprintf("%v => %v should be invalid", testData.prev, testData.current)
@@ -172,6 +190,37 @@ func TestShouldExpectMoreRecordFragment_Error(t *testing.T) {
 	}
 }
 
+// newRecordWriterAt returns a writer positioned at offset within the first block of dest. The space
+// before offset is taken by a single record, so that readers find a well formed log before offset.
+func newRecordWriterAt(t *testing.T, dest io.WriteSeeker, offset int) *RecordWriter {
+	w := NewRecordWriter(dest, 0)
+	if offset > 0 {
+		writeFailOnError(t, w, make([]byte, offset-recordHeaderSize))
+	}
+	return w
+}
+
+// truncateTestLog cuts the log file of fs short after size bytes, as if the writer died there.
+func truncateTestLog(t *testing.T, fs env.FS, size int) {
+	rewriteTestLog(t, fs, func(log []byte) []byte { return log[:size] })
+}
+
+// writeLogWithLostLastFragment returns a log of a record "first" followed by a FULL record "second",
+// from which the LAST fragment of "first" is missing, as if its write was lost while the next one made
+// it to the file. The FIRST fragment ends the first block with the single byte 'f'.
+func writeLogWithLostLastFragment(t *testing.T) env.FS {
+	fs, f := newTestLog(t)
+	rw := newRecordWriterAt(t, f, blockSize-recordHeaderSize-1)
+	writeFailOnError(t, rw, []byte("first"))
+	writeFailOnError(t, rw, []byte("second"))
+
+	lastFragmentEnd := blockSize + recordHeaderSize + len("irst")
+	rewriteTestLog(t, fs, func(log []byte) []byte {
+		return append(log[:blockSize:blockSize], log[lastFragmentEnd:]...)
+	})
+	return fs
+}
+
 func fill(buf []byte, n byte) {
 	for i := 0; i < len(buf); i++ {
 		buf[i] = n
@@ -180,9 +229,13 @@ func fill(buf []byte, n byte) {
 
 func readRecordAndVerify(t *testing.T, r *RecordReader, input []byte) {
 	resultBuf := new(bytes.Buffer)
-	if _, err := r.Read(resultBuf); err != nil {
+	n, err := r.Read(resultBuf)
+	if err != nil {
 		t.Fatal(err)
 	}
+	if n != len(input) {
+		t.Errorf("Expected Read to return %v but was %v", len(input), n)
+	}
 
 	if resultBuf.Len() != len(input) {
 		t.Fatalf("Expected %v but was %v", len(input), resultBuf.Len())
@@ -192,3 +245,410 @@ func readRecordAndVerify(t *testing.T, r *RecordReader, input []byte) {
 		t.Fatal("Expected contents to be equal but was not")
 	}
 }
+
+func TestRecordReader_LegacyChecksum(t *testing.T) {
+	input := []byte("hello world")
+	legacyRecord := func() io.ReadSeeker {
+		h := newHeader()
+		h.SetRecordType(FULL)
+		h.SetLength(uint16(len(input)))
+		h.SetChecksum(crc32.ChecksumIEEE(append([]byte{byte(FULL)}, input...)))
+		return bytes.NewReader(append(h, input...))
+	}
+
+	t.Run("Default options should reject IEEE checksums", func(t *testing.T) {
+		if _, err := NewRecordReader(legacyRecord(), 0).Read(new(bytes.Buffer)); err == nil {
+			t.Fatal("Expected a checksum error but got nil")
+		}
+	})
+
+	t.Run("LegacyChecksum should accept IEEE checksums", func(t *testing.T) {
+		readRecordAndVerify(t, NewRecordReaderWithOptions(legacyRecord(), 0, ReaderOptions{LegacyChecksum: true}), input)
+	})
+
+	t.Run("LegacyChecksum should accept masked crc32c checksums", func(t *testing.T) {
+		fs, f := newTestLog(t)
+		writeFailOnError(t, NewRecordWriter(f, 0), input)
+		readRecordAndVerify(t, NewRecordReaderWithOptions(openTestLog(t, fs), 0, ReaderOptions{LegacyChecksum: true}), input)
+	})
+}
+
+type recordingReporter struct {
+	droppedBytes []int
+	reasons      []error
+}
+
+func (r *recordingReporter) Corruption(bytes int, reason error) {
+	r.droppedBytes = append(r.droppedBytes, bytes)
+	r.reasons = append(r.reasons, reason)
+}
+
+// offsetRecordingReporter is a recordingReporter that also records where the dropped bytes start.
+type offsetRecordingReporter struct {
+	recordingReporter
+	offsets []int64
+}
+
+func (r *offsetRecordingReporter) CorruptionAt(offset int64, bytes int, reason error) {
+	r.offsets = append(r.offsets, offset)
+	r.Corruption(bytes, reason)
+}
+
+// Given a FULL record and the FIRST fragment of a second record in the first block, and the LAST
+// fragment of the second record followed by a third record in the next block, a bit flip in the
+// first record should drop the first block and resync at the second block.
+func TestRecordReader_Recovery_ChecksumMismatch_ShouldResyncAtNextBlock(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	writeFailOnError(t, w, []byte("first"))
+	writeFailOnError(t, w, make([]byte, blockSize))
+	writeFailOnError(t, w, []byte("third"))
+	rewriteTestLog(t, fs, func(log []byte) []byte {
+		log[recordHeaderSize] ^= 0x01
+		return log
+	})
+
+	reporter := new(recordingReporter)
+	rr := NewRecordReaderWithOptions(openTestLog(t, fs), 0, ReaderOptions{Reporter: reporter})
+	readRecordAndVerify(t, rr, []byte("third"))
+
+	secondRecordLastFragmentLength := blockSize - (blockSize - 2*recordHeaderSize - len("first"))
+	expectedDroppedBytes := []int{blockSize, secondRecordLastFragmentLength}
+	if !reflect.DeepEqual(reporter.droppedBytes, expectedDroppedBytes) {
+		t.Errorf("Expected %v dropped bytes but got %v", expectedDroppedBytes, reporter.droppedBytes)
+	}
+	if expectedErr := (RecordTypeMissmatchError{uninit, LAST}); reporter.reasons[1] != expectedErr {
+		t.Errorf("Expected '%v' but got '%v'", expectedErr, reporter.reasons[1])
+	}
+
+	verifyEOF(t, rr)
+}
+
+func TestRecordReader_Recovery_FirstPartialRecordSecondFullRecord_ShouldReturnSecondRecord(t *testing.T) {
+	fs := writeLogWithLostLastFragment(t)
+
+	reporter := new(recordingReporter)
+	rr := NewRecordReaderWithOptions(openTestLog(t, fs), blockSize-recordHeaderSize-1, ReaderOptions{Reporter: reporter})
+	readRecordAndVerify(t, rr, []byte("second"))
+
+	if !reflect.DeepEqual(reporter.droppedBytes, []int{1}) {
+		t.Errorf("Expected [1] dropped bytes but got %v", reporter.droppedBytes)
+	}
+	if expectedErr := (RecordTypeMissmatchError{FIRST, FULL}); reporter.reasons[0] != expectedErr {
+		t.Errorf("Expected '%v' but got '%v'", expectedErr, reporter.reasons[0])
+	}
+}
+
+func TestRecordReader_Recovery_TruncatedLastRecord_ShouldBeEOFWithoutReporting(t *testing.T) {
+	tests := map[string]int{
+		"Truncated header": 13,
+		"Truncated body":   14,
+	}
+
+	for testName, n := range tests {
+		t.Run(testName, func(t *testing.T) {
+			fs, f := newTestLog(t)
+			writeFailOnError(t, newRecordWriterAt(t, f, blockSize-recordHeaderSize), []byte("hello"))
+			truncateTestLog(t, fs, blockSize-recordHeaderSize+n)
+
+			reporter := new(recordingReporter)
+			verifyEOF(t, NewRecordReaderWithOptions(openTestLog(t, fs), blockSize-recordHeaderSize, ReaderOptions{Reporter: reporter}))
+			if len(reporter.droppedBytes) != 0 {
+				t.Errorf("Expected no corruption to be reported but got %v", reporter.reasons)
+			}
+		})
+	}
+}
+
+func verifyEOF(t *testing.T, r *RecordReader) {
+	if _, err := r.Read(new(bytes.Buffer)); err != io.EOF {
+		t.Fatalf("Expected '%v' but got '%v'", io.EOF, err)
+	}
+}
+
+func TestRecordReader_Next(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	records := [][]byte{[]byte("first"), repeat(50, 3*blockSize), []byte("third")}
+	for _, record := range records {
+		writeFailOnError(t, w, record)
+	}
+
+	rr := NewRecordReader(openTestLog(t, fs), 0)
+	secondRecordEnd := int64(recordHeaderSize+len("first")) + 4*recordHeaderSize + 3*blockSize
+	expectedOffsets := []int64{0, recordHeaderSize + int64(len("first")), secondRecordEnd}
+	for i, expected := range records {
+		if !rr.Next() {
+			t.Fatalf("Expected record %v but Next returned false with '%v'", i, rr.Err())
+		}
+		if !reflect.DeepEqual(rr.Record(), expected) {
+			t.Errorf("Expected contents of record %v to be equal but was not", i)
+		}
+		if rr.Offset() != expectedOffsets[i] {
+			t.Errorf("Expected record %v at offset %v but got %v", i, expectedOffsets[i], rr.Offset())
+		}
+	}
+
+	if rr.Next() || rr.Err() != nil {
+		t.Errorf("Expected (false, %v) at the end of the log but got (true, %v)", nil, rr.Err())
+	}
+}
+
+func TestRecordReader_Next_FullRecordShouldNotBeCopied(t *testing.T) {
+	fs, f := newTestLog(t)
+	writeFailOnError(t, NewRecordWriter(f, 0), []byte("hello world"))
+
+	rr := NewRecordReader(openTestLog(t, fs), 0)
+	if !rr.Next() {
+		t.Fatal(rr.Err())
+	}
+	if &rr.Record()[0] != &rr.block[recordHeaderSize] {
+		t.Error("Expected FULL record to be a slice of the block buffer")
+	}
+}
+
+func TestRecordReader_Next_Error(t *testing.T) {
+	fs := writeLogWithLostLastFragment(t)
+
+	rr := NewRecordReader(openTestLog(t, fs), blockSize-recordHeaderSize-1)
+	if rr.Next() {
+		t.Fatal("Expected Next to return false")
+	}
+	if expectedErr := (RecordTypeMissmatchError{FIRST, FULL}); rr.Err() != expectedErr {
+		t.Errorf("Expected '%v' but got '%v'", expectedErr, rr.Err())
+	}
+	if rr.Next() {
+		t.Error("Expected Next to keep returning false after an error")
+	}
+}
+
+func TestRecordReader_InitialOffset(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	records := [][]byte{[]byte("first"), repeat(50, 3*blockSize), []byte("third"), repeat(51, blockSize-62)}
+	for _, record := range records {
+		writeFailOnError(t, w, record)
+	}
+	writeFailOnError(t, w, []byte("fifth"))
+	records = append(records, []byte("fifth"))
+
+	const secondRecordOffset = recordHeaderSize + 5
+	const thirdRecordOffset = secondRecordOffset + 4*recordHeaderSize + 3*blockSize
+	tests := map[string]struct {
+		initialOffset       int64
+		expectedFirstRecord int
+	}{
+		"Offset at the start of the log":               {initialOffset: 0, expectedFirstRecord: 0},
+		"Offset within a FULL record":                  {initialOffset: 1, expectedFirstRecord: 1},
+		"Offset at the start of a FIRST fragment":      {initialOffset: secondRecordOffset, expectedFirstRecord: 1},
+		"Offset within a FIRST fragment":               {initialOffset: secondRecordOffset + 1, expectedFirstRecord: 2},
+		"Offset within a MIDDLE fragment":              {initialOffset: blockSize + 100, expectedFirstRecord: 2},
+		"Offset at the start of a MIDDLE fragment":     {initialOffset: 2 * blockSize, expectedFirstRecord: 2},
+		"Offset at the start of a FULL record":         {initialOffset: thirdRecordOffset, expectedFirstRecord: 2},
+		"Offset in the trailer of a block":             {initialOffset: 4*blockSize - 2, expectedFirstRecord: 4},
+		"Offset within a record followed by a trailer": {initialOffset: 3*blockSize + 100, expectedFirstRecord: 4},
+		"Offset after the start of the last record":    {initialOffset: 4*blockSize + 1, expectedFirstRecord: len(records)},
+	}
+
+	for testName, test := range tests {
+		for _, recovery := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%v (recovery=%v)", testName, recovery), func(t *testing.T) {
+				reporter := new(recordingReporter)
+				opts := ReaderOptions{}
+				if recovery {
+					opts.Reporter = reporter
+				}
+				rr := NewRecordReaderWithOptions(openTestLog(t, fs), test.initialOffset, opts)
+
+				for i := test.expectedFirstRecord; i < len(records); i++ {
+					if !rr.Next() {
+						t.Fatalf("Expected record %v but Next returned false with '%v'", i, rr.Err())
+					}
+					if !reflect.DeepEqual(rr.Record(), records[i]) {
+						t.Fatalf("Expected contents of record %v to be equal but was not", i)
+					}
+				}
+				if rr.Next() || rr.Err() != nil {
+					t.Errorf("Expected (false, %v) at the end of the log but got (true, %v)", nil, rr.Err())
+				}
+				if len(reporter.droppedBytes) != 0 {
+					t.Errorf("Expected no corruption to be reported but got %v", reporter.reasons)
+				}
+			})
+		}
+	}
+}
+
+// writeRecyclableLog returns a log of n records of roughly size bytes, written with logNumber.
+func writeRecyclableLog(t *testing.T, logNumber uint32, n int, size int) []byte {
+	fs, f := newTestLog(t)
+	w := NewRecordWriterWithOptions(f, 0, WriterOptions{Recyclable: true, LogNumber: logNumber})
+	for i := 0; i < n; i++ {
+		record := []byte(fmt.Sprintf("log %v record %v ", logNumber, i))
+		writeFailOnError(t, w, append(record, repeat(byte(i), size*(i%3+1))...))
+	}
+	return readTestLog(t, fs)
+}
+
+func TestRecordReader_Recyclable(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriterWithOptions(f, 0, WriterOptions{Recyclable: true, LogNumber: 7})
+	records := [][]byte{[]byte("first"), repeat(50, 3*blockSize), []byte("third"), repeat(51, blockSize-100), []byte("fifth")}
+	for _, record := range records {
+		writeFailOnError(t, w, record)
+	}
+
+	for _, logNumber := range []uint32{0, 7} {
+		t.Run(fmt.Sprintf("LogNumber %v", logNumber), func(t *testing.T) {
+			rr := NewRecordReaderWithOptions(openTestLog(t, fs), 0, ReaderOptions{LogNumber: logNumber})
+			for i, expected := range records {
+				if !rr.Next() {
+					t.Fatalf("Expected record %v but Next returned false with '%v'", i, rr.Err())
+				}
+				if !reflect.DeepEqual(rr.Record(), expected) {
+					t.Fatalf("Expected contents of record %v to be equal but was not", i)
+				}
+			}
+			if rr.Next() || rr.Err() != nil {
+				t.Errorf("Expected (false, %v) at the end of the log but got (true, %v)", nil, rr.Err())
+			}
+		})
+	}
+}
+
+func TestRecordReader_Recyclable_ShouldSkipTheTrailerOfABlock(t *testing.T) {
+	for padding := recordHeaderSize; padding < recyclableRecordHeaderSize; padding++ {
+		t.Run(fmt.Sprintf("%v bytes", padding), func(t *testing.T) {
+			fs, f := newTestLog(t)
+			w := NewRecordWriterWithOptions(f, 0, WriterOptions{Recyclable: true, LogNumber: 7})
+			records := [][]byte{repeat(50, blockSize-recyclableRecordHeaderSize-padding), repeat(51, 10), repeat(52, 20)}
+			for _, record := range records {
+				writeFailOnError(t, w, record)
+			}
+
+			rr := NewRecordReader(openTestLog(t, fs), 0)
+			var read [][]byte
+			for rr.Next() {
+				read = append(read, append([]byte(nil), rr.Record()...))
+			}
+			if !reflect.DeepEqual(read, records) || rr.Err() != nil {
+				t.Errorf("Expected %v records but got %v, '%v'", len(records), len(read), rr.Err())
+			}
+
+			follower := NewFollower(openTestLog(t, fs), 0, ReaderOptions{}, time.Millisecond)
+			for i := range records {
+				if _, _, err := follower.TryNext(); err != nil {
+					t.Fatalf("Expected the follower to read record %v but got '%v'", i, err)
+				}
+			}
+			if _, _, err := follower.TryNext(); err != ErrCaughtUp {
+				t.Errorf("Expected %v but got '%v'", ErrCaughtUp, err)
+			}
+		})
+	}
+}
+
+func TestRecordReader_ReusedFile_ShouldStopAtTheRecordsOfAnEarlierLog(t *testing.T) {
+	oldLog := writeRecyclableLog(t, 1, 100, 1000)
+	newLog := writeRecyclableLog(t, 2, 10, 900)
+	file := append(append([]byte(nil), newLog...), oldLog[len(newLog):]...)
+
+	for _, recovery := range []bool{false, true} {
+		t.Run(fmt.Sprintf("recovery=%v", recovery), func(t *testing.T) {
+			reporter := new(recordingReporter)
+			opts := ReaderOptions{LogNumber: 2}
+			if recovery {
+				opts.Reporter = reporter
+			}
+			rr := NewRecordReaderWithOptions(bytes.NewReader(file), 0, opts)
+
+			var read int
+			for ; rr.Next(); read++ {
+				if expected := fmt.Sprintf("log 2 record %v", read); string(rr.Record()[:len(expected)]) != expected {
+					t.Fatalf("Expected '%v' but got '%s'", expected, rr.Record()[:len(expected)])
+				}
+			}
+			if rr.Err() != nil || read != 10 {
+				t.Errorf("Expected (10 records, %v) but got (%v records, %v)", nil, read, rr.Err())
+			}
+			if len(reporter.droppedBytes) != 0 {
+				t.Errorf("Expected no corruption to be reported but got %v", reporter.reasons)
+			}
+		})
+	}
+}
+
+func TestRecordReader_UnknownRecordType(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	writeFailOnError(t, w, []byte("first"))
+	w.writeRecordFragment(recordType(42), []byte("from the future"))
+	writeFailOnError(t, w, []byte("second"))
+
+	t.Run("Should be an error by default", func(t *testing.T) {
+		rr := NewRecordReader(openTestLog(t, fs), 0)
+		readRecordAndVerify(t, rr, []byte("first"))
+		_, err := rr.Read(new(bytes.Buffer))
+		if expectedErr := (RecordTypeMissmatchError{uninit, recordType(42)}); err != expectedErr {
+			t.Errorf("Expected '%v' but got '%v'", expectedErr, err)
+		}
+	})
+
+	t.Run("Should be skipped and counted with SkipUnknownTypes", func(t *testing.T) {
+		rr := NewRecordReaderWithOptions(openTestLog(t, fs), 0, ReaderOptions{SkipUnknownTypes: true})
+		readRecordAndVerify(t, rr, []byte("first"))
+		readRecordAndVerify(t, rr, []byte("second"))
+		verifyEOF(t, rr)
+		if rr.SkippedRecords() != 1 {
+			t.Errorf("Expected 1 skipped record but got %v", rr.SkippedRecords())
+		}
+	})
+}
+
+func TestRecordReader_UnknownRecordTypeWithinAFragmentedRecord_ShouldBeSkipped(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	w.writeRecordFragment(FIRST, []byte("hello "))
+	w.writeRecordFragment(recordType(42), []byte("from the future"))
+	w.writeRecordFragment(LAST, []byte("world"))
+	w.Flush()
+
+	rr := NewRecordReaderWithOptions(openTestLog(t, fs), 0, ReaderOptions{SkipUnknownTypes: true})
+	readRecordAndVerify(t, rr, []byte("hello world"))
+}
+
+func TestRecordReader_Metadata(t *testing.T) {
+	type metadata struct {
+		t      MetadataType
+		record string
+	}
+
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	writeFailOnError(t, w, []byte("first"))
+	if _, err := w.WriteMetadata(3, []byte("trace 1")); err != nil {
+		t.Fatal(err)
+	}
+	writeFailOnError(t, w, repeat(50, blockSize-30))
+	// Does not fit in what is left of the first block.
+	if _, err := w.WriteMetadata(MaxMetadataType, []byte("trace 2")); err != nil {
+		t.Fatal(err)
+	}
+	writeFailOnError(t, w, []byte("third"))
+
+	var read []metadata
+	rr := NewRecordReaderWithOptions(openTestLog(t, fs), 0, ReaderOptions{
+		Metadata: func(t MetadataType, record []byte) {
+			read = append(read, metadata{t, string(record)})
+		},
+	})
+	readRecordAndVerify(t, rr, []byte("first"))
+	readRecordAndVerify(t, rr, repeat(50, blockSize-30))
+	readRecordAndVerify(t, rr, []byte("third"))
+	verifyEOF(t, rr)
+
+	expected := []metadata{{3, "trace 1"}, {MaxMetadataType, "trace 2"}}
+	if !reflect.DeepEqual(read, expected) {
+		t.Errorf("Expected %v but got %v", expected, read)
+	}
+}
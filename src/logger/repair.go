@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"errors"
+	"io"
+)
+
+var errorTruncatedRecord = errors.New("log ends within a record")
+
+// RepairOptions control how Repair reads the damaged log and writes the repaired one.
+type RepairOptions struct {
+	// Reader are the options the damaged log is read with. Its Reporter, if any, is told about the
+	// dropped bytes as well, and its Metadata callback is called before the metadata record is copied.
+	Reader ReaderOptions
+	// Writer are the options the repaired log is written with.
+	Writer WriterOptions
+}
+
+// RepairReport describes what Repair salvaged and what it dropped.
+type RepairReport struct {
+	// Records and RecordBytes count the user records copied to the repaired log, and Metadata the
+	// metadata records.
+	Records     int64
+	RecordBytes int64
+	Metadata    int64
+	// Dropped lists the damaged parts of the log, in the order they were found.
+	Dropped []DroppedBytes
+}
+
+// DroppedBytes are bytes of a damaged log that Repair could not salvage.
+type DroppedBytes struct {
+	// Offset is where in the damaged log the dropped bytes start.
+	Offset int64
+	Bytes  int
+	Reason error
+}
+
+// Repair copies every intact record of the damaged log src to dest, as a RecordReader in recovery mode
+// finds them, and reports what it had to drop. Metadata records are copied as well, unless dest is a
+// recyclable log. src is only ever read from.
+//
+// The records are flushed but not synced when Repair returns. An error is returned when src or dest
+// fail, or when a record fails authentication, see ErrTampered.
+func Repair(src io.ReadSeeker, dest io.WriteSeeker, opts RepairOptions) (RepairReport, error) {
+	var report RepairReport
+	w := NewRecordWriterWithOptions(dest, 0, opts.Writer)
+
+	readerOpts := opts.Reader
+	readerOpts.Reporter = &repairReporter{report: &report, reporter: opts.Reader.Reporter}
+	readerOpts.Metadata = func(t MetadataType, record []byte) {
+		if opts.Reader.Metadata != nil {
+			opts.Reader.Metadata(t, record)
+		}
+		if _, err := w.WriteMetadata(t, record); err == nil {
+			report.Metadata++
+		}
+	}
+	rr := NewRecordReaderWithOptions(src, 0, readerOpts)
+
+	for rr.Next() {
+		if _, err := w.Write(rr.Record()); err != nil {
+			return report, err
+		}
+		report.Records++
+		report.RecordBytes += int64(len(rr.Record()))
+	}
+	if rr.Err() != nil {
+		return report, rr.Err()
+	}
+	if offset, bytes := rr.Truncated(); bytes > 0 {
+		report.Dropped = append(report.Dropped, DroppedBytes{offset, bytes, errorTruncatedRecord})
+	}
+	return report, w.Flush()
+}
+
+// repairReporter adds the corruption found by Repair to its report.
+type repairReporter struct {
+	report   *RepairReport
+	reporter Reporter
+}
+
+// Corruption is only called for corruption that is not at an offset in the log, which Repair does not
+// come across.
+func (r *repairReporter) Corruption(bytes int, reason error) {
+	r.CorruptionAt(-1, bytes, reason)
+}
+
+func (r *repairReporter) CorruptionAt(offset int64, bytes int, reason error) {
+	r.report.Dropped = append(r.report.Dropped, DroppedBytes{offset, bytes, reason})
+	if r.reporter != nil {
+		r.reporter.Corruption(bytes, reason)
+	}
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// damagedLog returns a log of four records of which the second is damaged and the last is cut short.
+func damagedLog(t *testing.T) []byte {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	writeFailOnError(t, w, []byte("first"))
+	writeFailOnError(t, w, repeat(50, blockSize))
+	if _, err := w.WriteMetadata(3, []byte("trace")); err != nil {
+		t.Fatal(err)
+	}
+	writeFailOnError(t, w, []byte("third"))
+	writeFailOnError(t, w, []byte("fourth"))
+
+	log := readTestLog(t, fs)
+	log[2*recordHeaderSize+5+10] ^= 1
+	return log[:len(log)-2]
+}
+
+func TestRepair(t *testing.T) {
+	log := damagedLog(t)
+	original := append([]byte{}, log...)
+	repairedFS, repaired := newTestLog(t)
+
+	reporter := new(recordingReporter)
+	report, err := Repair(bytes.NewReader(log), repaired, RepairOptions{Reader: ReaderOptions{Reporter: reporter}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second record ends with a LAST fragment of lastLength bytes at the start of the second block.
+	const lastLength = blockSize - (blockSize - 2*recordHeaderSize - 5)
+	const fourthOffset = blockSize + 3*recordHeaderSize + lastLength + 2*5
+	expected := RepairReport{
+		Records:     2,
+		RecordBytes: 10,
+		Metadata:    1,
+		Dropped: []DroppedBytes{
+			{recordHeaderSize + 5, blockSize - recordHeaderSize - 5, report.Dropped[0].Reason},
+			{blockSize, lastLength, RecordTypeMissmatchError{uninit, LAST}},
+			{fourthOffset, recordHeaderSize + 4, errorTruncatedRecord},
+		},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, report)
+	}
+	if len(reporter.droppedBytes) != 2 {
+		t.Errorf("Expected the Reporter to be told about 2 corruptions but got %v", reporter.droppedBytes)
+	}
+	if !bytes.Equal(log, original) {
+		t.Error("Expected the damaged log to be left as it was")
+	}
+
+	var metadata []string
+	rr := NewRecordReaderWithOptions(openTestLog(t, repairedFS), 0, ReaderOptions{
+		Metadata: func(t MetadataType, record []byte) { metadata = append(metadata, string(record)) },
+	})
+	readRecordAndVerify(t, rr, []byte("first"))
+	readRecordAndVerify(t, rr, []byte("third"))
+	verifyEOF(t, rr)
+	if !reflect.DeepEqual(metadata, []string{"trace"}) {
+		t.Errorf("Expected the metadata record to be copied but got %v", metadata)
+	}
+}
+
+func TestRepair_ShouldWriteTheRepairedLogWithTheWriterOptions(t *testing.T) {
+	repairedFS, repaired := newTestLog(t)
+	opts := RepairOptions{Writer: WriterOptions{Recyclable: true, LogNumber: 7}}
+	report, err := Repair(bytes.NewReader(damagedLog(t)), repaired, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Metadata != 0 {
+		t.Errorf("Expected no metadata records in a recyclable log but got %v", report.Metadata)
+	}
+
+	rr := NewRecordReaderWithOptions(openTestLog(t, repairedFS), 0, ReaderOptions{LogNumber: 7})
+	readRecordAndVerify(t, rr, []byte("first"))
+	readRecordAndVerify(t, rr, []byte("third"))
+	verifyEOF(t, rr)
+}
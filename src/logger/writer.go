@@ -1,57 +1,149 @@
 package logger
 
 import (
+	"errors"
 	"hash"
-	"hash/crc32"
 	"io"
+
+	"crc32c"
 )
 
 type RecordWriter struct {
-	dest        *trackingWriter
+	dest        io.Writer
 	blockOffset uint32
 	h           hash.Hash32
 
-	header header
+	header     header
+	headerSize uint32
+	opts       WriterOptions
+
+	compressor compressor
+	stats      CompressionStats
+	cipher     *recordCipher
+
+	// buf holds the part of the current block that has not been written to dest yet.
+	buf []byte
+	// err is the first error returned by dest. Once set, every write fails with it.
+	err error
+
+	// offset is the offset in dest just past the last byte written to buf.
+	offset           int64
+	lastRecordOffset int64
+}
+
+// WriterOptions control how a RecordWriter encodes the log.
+type WriterOptions struct {
+	// Recyclable makes the writer use the recyclable record types, whose header carries LogNumber.
+	// Logs written to reused files must be recyclable so that readers do not mistake records left
+	// over from an earlier use of the file for records of this log.
+	Recyclable bool
+	LogNumber  uint32
+
+	// Compression is the algorithm user records are compressed with before they are split into
+	// fragments. A record that does not get smaller is stored uncompressed.
+	Compression Compression
+
+	// Keys makes the writer encrypt user records with AES-GCM, using the current key of Keys. The log
+	// starts with a PREAMBLE record that names the key, so an encrypted log can only be written from
+	// the start, and cannot be recyclable. Metadata records are not encrypted.
+	Keys KeyProvider
+}
+
+// WriteOptions control a single write to a RecordWriter.
+type WriteOptions struct {
+	// Sync makes the write flush the buffer and sync dest before it returns, see RecordWriter.Sync.
+	Sync bool
 }
 
 // NewRecordWriter creates a writer that writes recods to the dest WriteSeeker.
 // The WriteSeeker would be seeked only once and would be seeked to destLength relative to the start of the file.
+//
+// Records are buffered a block at a time. They reach dest when a block is complete or on Flush and Sync.
 func NewRecordWriter(dest io.WriteSeeker, destLength int64) *RecordWriter {
+	return NewRecordWriterWithOptions(dest, destLength, WriterOptions{})
+}
+
+// NewRecordWriterWithOptions creates a writer like NewRecordWriter that encodes records as opts asks for.
+func NewRecordWriterWithOptions(dest io.WriteSeeker, destLength int64, opts WriterOptions) *RecordWriter {
+	h, headerSize := newHeader(), uint32(recordHeaderSize)
+	if opts.Recyclable {
+		h, headerSize = newRecyclableHeader(), recyclableRecordHeaderSize
+		h.SetLogNumber(opts.LogNumber)
+	}
+
 	dest.Seek(destLength, io.SeekStart)
-	return &RecordWriter{
-		dest:        &trackingWriter{dest: dest},
+	w := &RecordWriter{
+		dest:        dest,
 		blockOffset: uint32(destLength % blockSize),
-		h:           crc32.NewIEEE(),
-		header:      newHeader(),
+		h:           crc32c.New(),
+		header:      h,
+		headerSize:  headerSize,
+		opts:        opts,
+		compressor:  compressor{compression: opts.Compression},
+		buf:         make([]byte, 0, blockSize),
+		offset:      destLength,
+	}
+	if opts.Keys != nil {
+		w.startEncryptedLog()
+	}
+	return w
+}
+
+// startEncryptedLog writes the PREAMBLE record of an encrypted log. Any error fails every write.
+func (w *RecordWriter) startEncryptedLog() {
+	switch {
+	case w.opts.Recyclable:
+		w.err = errorEncryptionInRecyclableLog
+	case w.offset != 0:
+		w.err = errorEncryptedAppend
+	default:
+		var preamble []byte
+		if w.cipher, preamble, w.err = newWriterCipher(w.opts.Keys); w.err == nil {
+			w.writeRecordFragment(PREAMBLE, preamble)
+		}
 	}
 }
 
-var sixEmptyBytes = []byte{0, 0, 0, 0, 0, 0}
+// emptyTrailer holds the zeros that fill the end of a block that is too short for a header.
+var emptyTrailer = make([]byte, recyclableRecordHeaderSize-1)
 
 // Write writes record to the dest writer.
 //
 // It returns the number of bytes written to the writer.
 // This number > len(p) as it includes record header and each fragment as described in package doc.
 func (w *RecordWriter) Write(p []byte) (int, error) {
-	w.dest.Reset()
+	return w.WriteWithOptions(p, WriteOptions{})
+}
 
+// WriteWithOptions writes record p like Write and then flushes and syncs as opts asks for.
+func (w *RecordWriter) WriteWithOptions(p []byte, opts WriteOptions) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	record := p
+	p, compressed := w.compressor.compress(record)
+	w.stats.add(len(record), len(p))
+	if w.cipher != nil {
+		if p, w.err = w.cipher.seal(p, w.nextRecordOffset()); w.err != nil {
+			return 0, w.err
+		}
+	}
+
+	var n int
 	var start uint32
 	var end uint32
 	isFirstRecord := true
 	last := uint32(len(p))
 
-	for end < last && w.dest.err == nil {
-		switch remainingInBlock := blockSize - w.blockOffset; remainingInBlock {
-		case 6:
-			w.dest.Write(sixEmptyBytes)
-			w.blockOffset = 0
-			if w.dest.err != nil {
-				break
-			}
-		case 0:
-			w.blockOffset = 0
+	// An empty record is written as a FULL fragment with no data, like LevelDB does, so the loop runs at
+	// least once.
+	for (isFirstRecord || end < last) && w.err == nil {
+		if remainingInBlock := blockSize - w.blockOffset; remainingInBlock < w.headerSize {
+			// A header does not fit, fill the trailer with zeros and switch to a new block.
+			n += w.append(emptyTrailer[:remainingInBlock])
+			continue
 		}
-		availableForData := blockSize - w.blockOffset - recordHeaderSize
+		availableForData := blockSize - w.blockOffset - w.headerSize
 		end = last
 		if end-start > availableForData {
 			end = start + availableForData
@@ -65,43 +157,121 @@ func (w *RecordWriter) Write(p []byte) (int, error) {
 			recordType = LAST
 		}
 
+		if isFirstRecord {
+			w.lastRecordOffset = w.offset
+			if compressed {
+				recordType |= compressedFlag
+			}
+		}
 		isFirstRecord = false
-		w.writeRecordFragment(recordType, p[start:end])
+		n += w.writeRecordFragment(recordType, p[start:end])
 		start = end
 	}
 
-	return w.dest.n, w.dest.err
+	if opts.Sync {
+		w.Sync()
+	}
+	return n, w.err
 }
 
-func (w *RecordWriter) writeRecordFragment(rt recordType, p []byte) {
-	w.header.SetLength(uint16(len(p)))
-	w.header.SetRecordType(rt)
+var errorMetadataInRecyclableLog = errors.New("logger: metadata records cannot be written to a recyclable log")
+var errorMetadataTooLarge = errors.New("logger: metadata record does not fit in a block")
+var errorInvalidMetadataType = errors.New("logger: invalid metadata type")
 
-	w.h.Reset()
-	w.h.Write(w.header.RecordTypeByte())
-	w.h.Write(p)
-	w.header.SetChecksum(w.h.Sum32())
+// WriteMetadata writes p as a metadata record of type t. It returns the number of bytes written like Write.
+//
+// A metadata record is written as a single fragment, so p must fit in a block. When it does not fit in
+// what is left of the current block, the rest of the block is filled with zeros. Readers pass metadata
+// records to ReaderOptions.Metadata and never return them as records.
+func (w *RecordWriter) WriteMetadata(t MetadataType, p []byte) (int, error) {
+	switch {
+	case t > MaxMetadataType:
+		return 0, errorInvalidMetadataType
+	case w.opts.Recyclable:
+		return 0, errorMetadataInRecyclableLog
+	case uint32(len(p)) > blockSize-w.headerSize:
+		return 0, errorMetadataTooLarge
+	}
 
-	w.dest.Write(w.header)
-	w.dest.Write(p)
-	w.blockOffset += recordHeaderSize + uint32(len(p))
+	var n int
+	if remainingInBlock := blockSize - w.blockOffset; remainingInBlock < w.headerSize+uint32(len(p)) {
+		// Readers skip the rest of a block that starts with a zero filled header.
+		n += w.append(make([]byte, remainingInBlock))
+	}
+	if w.err == nil {
+		w.lastRecordOffset = w.offset
+		n += w.writeRecordFragment(t.recordType(), p)
+	}
+	return n, w.err
 }
 
-type trackingWriter struct {
-	dest io.Writer
+// nextRecordOffset returns the offset in dest that the next record starts at, which is past the trailer
+// when a header does not fit in what is left of the block.
+func (w *RecordWriter) nextRecordOffset() int64 {
+	if remainingInBlock := blockSize - w.blockOffset; remainingInBlock < w.headerSize {
+		return w.offset + int64(remainingInBlock)
+	}
+	return w.offset
+}
 
-	n   int
-	err error
+// CompressionStats returns the sizes of the user records written so far before and after compression.
+func (w *RecordWriter) CompressionStats() CompressionStats {
+	return w.stats
 }
 
-func (tw *trackingWriter) Write(p []byte) {
-	if tw.err == nil {
-		_, tw.err = tw.dest.Write(p)
+// LastRecordOffset returns the offset in dest of the first fragment of the last record written.
+func (w *RecordWriter) LastRecordOffset() int64 {
+	return w.lastRecordOffset
+}
+
+// Flush writes the buffered part of the current block to dest.
+func (w *RecordWriter) Flush() error {
+	if w.err == nil && len(w.buf) > 0 {
+		_, w.err = w.dest.Write(w.buf)
+		w.buf = w.buf[:0]
 	}
-	tw.n += len(p)
+	return w.err
 }
 
-func (tw *trackingWriter) Reset() {
-	tw.n = 0
-	tw.err = nil
+// Sync flushes the buffer and then commits dest to stable storage, if dest has a Sync method like *os.File.
+// Records are only durable once Sync returns without an error.
+func (w *RecordWriter) Sync() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if s, ok := w.dest.(syncer); ok {
+		w.err = s.Sync()
+	}
+	return w.err
+}
+
+type syncer interface {
+	Sync() error
+}
+
+func (w *RecordWriter) writeRecordFragment(rt recordType, p []byte) int {
+	if w.opts.Recyclable {
+		rt = rt.recyclable()
+	}
+	w.header.SetLength(uint16(len(p)))
+	w.header.SetRecordType(rt)
+
+	w.h.Reset()
+	w.h.Write(w.header.ChecksummedBytes())
+	w.h.Write(p)
+	w.header.SetChecksum(crc32c.Mask(w.h.Sum32()))
+
+	return w.append(w.header) + w.append(p)
+}
+
+// append adds p to the current block and writes the block to dest once it is complete.
+func (w *RecordWriter) append(p []byte) int {
+	w.buf = append(w.buf, p...)
+	w.blockOffset += uint32(len(p))
+	w.offset += int64(len(p))
+	if w.blockOffset == blockSize {
+		w.Flush()
+		w.blockOffset = 0
+	}
+	return len(p)
 }
@@ -19,6 +19,27 @@ func TestWriteLargeRecordToTempFile(t *testing.T) {
 	verifyWrittenLength(t, writtenLen, 20*blockSize)
 }
 
+func TestWriteWithSyncToTempFile(t *testing.T) {
+	f, err := ioutil.TempFile(os.TempDir(), t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	defer os.Remove(f.Name())
+	w := NewRecordWriter(f, 0)
+
+	if _, err := w.WriteWithOptions([]byte("hello world"), WriteOptions{Sync: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := os.Open(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	readRecordAndVerify(t, NewRecordReader(r, 0), []byte("hello world"))
+}
+
 func BenchmarkWriteLogToTempFile(b *testing.B) {
 	f, err := ioutil.TempFile(os.TempDir(), b.Name())
 	if err != nil {
@@ -1,34 +1,39 @@
 package logger
 
 import (
+	"bytes"
 	"encoding/binary"
-	"hash/crc32"
+	"io"
 	"reflect"
 	"testing"
+
+	"crc32c"
+	"env"
 )
 
 func TestWriteRecordInBlock(t *testing.T) {
-	buf := new(OnlyOnceSeekableBuffer)
-	w := NewRecordWriter(buf, 0)
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
 	input := []byte("hello world")
 
 	writtenLen := writeFailOnError(t, w, input)
 
-	verifyRecord(t, buf.Bytes(), []byte(input), FULL)
+	verifyRecord(t, readTestLog(t, fs), []byte(input), FULL)
 
 	verifyBlockOffset(t, w.blockOffset, recordHeaderSize+uint32(len(input)))
 	verifyWrittenLength(t, writtenLen, recordHeaderSize+len(input))
 }
 
 func TestWrite_WhenBlockHasLessThan6Bytes_ShouldFillEndOfBlockWith6ZeroBytes(t *testing.T) {
-	buf := new(OnlyOnceSeekableBuffer)
-	w := NewRecordWriter(buf, blockSize-(recordHeaderSize-1))
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, blockSize-(recordHeaderSize-1))
 	input := []byte("hello world")
 
 	writtenLen := writeFailOnError(t, w, input)
+	buf := writtenFrom(t, fs, blockSize-(recordHeaderSize-1))
 
-	if zeroFill := buf.Next(6); !reflect.DeepEqual(zeroFill, sixEmptyBytes) {
-		t.Errorf("Expected '%v' but found '%v'", sixEmptyBytes, zeroFill)
+	if zeroFill := buf.Next(6); !reflect.DeepEqual(zeroFill, emptyTrailer[:6]) {
+		t.Errorf("Expected '%v' but found '%v'", emptyTrailer[:6], zeroFill)
 	}
 
 	verifyRecord(t, buf.Bytes(), []byte(input), FULL)
@@ -37,13 +42,32 @@ func TestWrite_WhenBlockHasLessThan6Bytes_ShouldFillEndOfBlockWith6ZeroBytes(t *
 	verifyBlockOffset(t, w.blockOffset, recordHeaderSize+uint32(len(input)))
 }
 
+func TestWrite_WhenBlockHas3Bytes_ShouldFillEndOfBlockWith3ZeroBytes(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, blockSize-3)
+	input := []byte("hello world")
+
+	writtenLen := writeFailOnError(t, w, input)
+	buf := writtenFrom(t, fs, blockSize-3)
+
+	if zeroFill := buf.Next(3); !reflect.DeepEqual(zeroFill, emptyTrailer[:3]) {
+		t.Errorf("Expected '%v' but found '%v'", emptyTrailer[:3], zeroFill)
+	}
+
+	verifyRecord(t, buf.Bytes(), []byte(input), FULL)
+
+	verifyWrittenLength(t, writtenLen, recordHeaderSize+len(input)+3)
+	verifyBlockOffset(t, w.blockOffset, recordHeaderSize+uint32(len(input)))
+}
+
 func TestWrite_WhenBlockHas7Bytes_ShouldCreateAZeroLengthRecordWithRecordTypeFirst(t *testing.T) {
-	buf := new(OnlyOnceSeekableBuffer)
-	w := NewRecordWriter(buf, blockSize-recordHeaderSize)
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, blockSize-recordHeaderSize)
 
 	input := []byte("hello world")
 
 	writtenLen := writeFailOnError(t, w, input)
+	buf := writtenFrom(t, fs, blockSize-recordHeaderSize)
 
 	verifyRecord(t, buf.Next(7), []byte{}, FIRST)
 
@@ -53,13 +77,29 @@ func TestWrite_WhenBlockHas7Bytes_ShouldCreateAZeroLengthRecordWithRecordTypeFir
 	verifyBlockOffset(t, w.blockOffset, recordHeaderSize+uint32(len(input)))
 }
 
+func TestWrite_EmptyRecord_ShouldWriteAZeroLengthFullRecord(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+	writeFailOnError(t, w, []byte("hello"))
+
+	writtenLen := writeFailOnError(t, w, nil)
+
+	buf := writtenFrom(t, fs, recordHeaderSize+len("hello"))
+	verifyRecord(t, buf.Bytes(), []byte{}, FULL)
+	verifyWrittenLength(t, writtenLen, recordHeaderSize)
+	if offset := w.LastRecordOffset(); offset != recordHeaderSize+int64(len("hello")) {
+		t.Errorf("Expected the empty record at offset %v but got %v", recordHeaderSize+len("hello"), offset)
+	}
+}
+
 func TestWrite_WhenRecodsSpansOver3Blocks_ShouldCreateFirstMiddleLastRecods(t *testing.T) {
-	buf := new(OnlyOnceSeekableBuffer)
-	w := NewRecordWriter(buf, 0)
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
 
 	writtenLen := writeFailOnError(t, w, make([]byte, 3*(blockSize-recordHeaderSize)))
 	verifyWrittenLength(t, writtenLen, 3*blockSize)
 
+	buf := writtenFrom(t, fs, 0)
 	for _, expectedRecordType := range []recordType{FIRST, MIDDLE, LAST} {
 		record := buf.Next(blockSize)
 		verifyRecordType(t, record, expectedRecordType)
@@ -67,24 +107,218 @@ func TestWrite_WhenRecodsSpansOver3Blocks_ShouldCreateFirstMiddleLastRecods(t *t
 	}
 }
 
+func TestWrite_ShouldBufferTheBlockUntilFlush(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+
+	if _, err := w.Write([]byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(readTestLog(t, fs)); n != 0 {
+		t.Fatalf("Expected nothing to be written before Flush but got %v bytes", n)
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	verifyRecord(t, readTestLog(t, fs), []byte("hello world"), FULL)
+}
+
+func TestWrite_ShouldWriteCompleteBlocksWithoutFlush(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+
+	writtenLen, err := w.Write(make([]byte, 2*blockSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(readTestLog(t, fs)); n != 2*blockSize {
+		t.Errorf("Expected %v bytes to be written before Flush but got %v", 2*blockSize, n)
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	verifyWrittenLength(t, len(readTestLog(t, fs)), writtenLen)
+}
+
+func TestWriteWithOptions_Sync(t *testing.T) {
+	tests := map[string]struct {
+		opts          WriteOptions
+		expectedLen   int
+		expectedSyncs int
+	}{
+		"Without sync the record should stay buffered": {
+			opts:          WriteOptions{},
+			expectedLen:   0,
+			expectedSyncs: 0,
+		},
+		"With sync the record should be flushed and synced": {
+			opts:          WriteOptions{Sync: true},
+			expectedLen:   recordHeaderSize + len("hello world"),
+			expectedSyncs: 1,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			fs, f := newTestLog(t)
+			w := NewRecordWriter(f, 0)
+
+			if _, err := w.WriteWithOptions([]byte("hello world"), test.opts); err != nil {
+				t.Fatal(err)
+			}
+			if n := len(readTestLog(t, fs)); n != test.expectedLen || fs.Syncs() != test.expectedSyncs {
+				t.Errorf("Expected (len=%v, syncs=%v) but got (len=%v, syncs=%v)",
+					test.expectedLen, test.expectedSyncs, n, fs.Syncs())
+			}
+		})
+	}
+}
+
+func TestSync_ShouldFlushAndSync(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriter(f, 0)
+
+	writeFailOnError(t, w, []byte("hello"))
+	if _, err := w.Write([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	verifyWrittenLength(t, len(readTestLog(t, fs)), 2*recordHeaderSize+len("helloworld"))
+	if fs.Syncs() != 1 {
+		t.Errorf("Expected 1 sync but got %v", fs.Syncs())
+	}
+}
+
+func TestWrite_WhenDestFails_ShouldFailEveryFollowingWrite(t *testing.T) {
+	fs, f := newTestLog(t)
+	fs.FailWrite(1)
+	w := NewRecordWriter(f, 0)
+	expectedErr := env.ErrInjected
+
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != expectedErr {
+		t.Fatalf("Expected '%v' but got '%v'", expectedErr, err)
+	}
+	if _, err := w.Write([]byte("world")); err != expectedErr {
+		t.Errorf("Expected '%v' but got '%v'", expectedErr, err)
+	}
+	if err := w.Sync(); err != expectedErr {
+		t.Errorf("Expected '%v' but got '%v'", expectedErr, err)
+	}
+}
+
+func TestLastRecordOffset_ShouldSkipTheTrailer(t *testing.T) {
+	_, f := newTestLog(t)
+	w := NewRecordWriter(f, blockSize-3)
+
+	writeFailOnError(t, w, []byte("hello"))
+	if w.LastRecordOffset() != blockSize {
+		t.Errorf("Expected %v but got %v", blockSize, w.LastRecordOffset())
+	}
+
+	writeFailOnError(t, w, []byte("world"))
+	if expected := int64(blockSize + recordHeaderSize + len("hello")); w.LastRecordOffset() != expected {
+		t.Errorf("Expected %v but got %v", expected, w.LastRecordOffset())
+	}
+}
+
+func TestWrite_Recyclable_ShouldWriteTheLogNumberInTheHeader(t *testing.T) {
+	fs, f := newTestLog(t)
+	w := NewRecordWriterWithOptions(f, blockSize-recyclableRecordHeaderSize+1, WriterOptions{Recyclable: true, LogNumber: 42})
+	input := []byte("hello world")
+
+	writtenLen := writeFailOnError(t, w, input)
+	buf := writtenFrom(t, fs, blockSize-recyclableRecordHeaderSize+1)
+
+	if zeroFill := buf.Next(10); !reflect.DeepEqual(zeroFill, emptyTrailer) {
+		t.Errorf("Expected '%v' but found '%v'", emptyTrailer, zeroFill)
+	}
+	record := buf.Bytes()
+	verifyRecordLength(t, record, len(input))
+	verifyRecordType(t, record, RecyclableFULL)
+	if logNumber := binary.LittleEndian.Uint32(record[7:11]); logNumber != 42 {
+		t.Errorf("Expected log number 42 but got %v", logNumber)
+	}
+	expectedCheckSum := crc32c.Mask(crc32c.Checksum(append(record[6:11:11], input...)))
+	if actualCheckSum := binary.LittleEndian.Uint32(record[0:4]); expectedCheckSum != actualCheckSum {
+		t.Errorf("Expected %v checksum but was %v", expectedCheckSum, actualCheckSum)
+	}
+	if data := string(record[11:]); data != string(input) {
+		t.Errorf("Expected '%v' but got '%v'", string(input), data)
+	}
+	verifyWrittenLength(t, writtenLen, 10+recyclableRecordHeaderSize+len(input))
+}
+
+func TestWriteMetadata_Error(t *testing.T) {
+	tests := map[string]struct {
+		opts          WriterOptions
+		t             MetadataType
+		record        []byte
+		expectedError error
+	}{
+		"Metadata type out of range": {
+			t:             MaxMetadataType + 1,
+			expectedError: errorInvalidMetadataType,
+		},
+		"Metadata record larger than a block": {
+			record:        make([]byte, blockSize-recordHeaderSize+1),
+			expectedError: errorMetadataTooLarge,
+		},
+		"Recyclable log": {
+			opts:          WriterOptions{Recyclable: true},
+			expectedError: errorMetadataInRecyclableLog,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, f := newTestLog(t)
+			w := NewRecordWriterWithOptions(f, 0, test.opts)
+			if _, err := w.WriteMetadata(test.t, test.record); err != test.expectedError {
+				t.Errorf("Expected '%v' but got '%v'", test.expectedError, err)
+			}
+		})
+	}
+}
+
 func BenchmarkWriteRecord(b *testing.B) {
-	buf := new(OnlyOnceSeekableBuffer)
-	w := NewRecordWriter(buf, 0)
+	fs := env.NewMemFS()
+	f, err := fs.Create(testLogName)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := NewRecordWriter(f, 0)
 
 	record := make([]byte, 1023)
 	for i := 0; i < b.N; i++ {
 		if _, err := w.Write(record); err != nil {
 			b.Fatal(err)
 		}
-		buf.Reset()
+		// Keep rewriting the start of the file so that it does not grow with b.N.
+		f.Seek(0, io.SeekStart)
 	}
 }
 
+// writtenFrom returns what was written to the log file of fs from offset on, which is where a writer
+// created at offset started.
+func writtenFrom(t *testing.T, fs env.FS, offset int) *bytes.Buffer {
+	return bytes.NewBuffer(readTestLog(t, fs)[offset:])
+}
+
 func writeFailOnError(t *testing.T, w *RecordWriter, input []byte) int {
 	writtenLen, err := w.Write(input)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
 	return writtenLen
 }
 
@@ -102,7 +336,7 @@ func verifyRecordLength(t *testing.T, record []byte, length int) {
 
 func verifyRecordTypeAndChecksum(t *testing.T, record []byte, recordType recordType, input []byte) {
 	verifyRecordType(t, record, recordType)
-	expectedCheckSum := crc32.ChecksumIEEE(append([]byte{record[6]}, input...))
+	expectedCheckSum := crc32c.Mask(crc32c.Checksum(append([]byte{record[6]}, input...)))
 	if actualCheckSum := binary.LittleEndian.Uint32(record[0:4]); expectedCheckSum != actualCheckSum {
 		t.Errorf("Expected %v checksum but was %v", expectedCheckSum, actualCheckSum)
 	}
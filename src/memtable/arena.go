@@ -0,0 +1,48 @@
+package memtable
+
+import "sync/atomic"
+
+// arenaBlockSize is the size of the blocks an arena hands out memory from.
+const arenaBlockSize = 4096
+
+// arena hands out memory for the entries of a memtable from large blocks, so that a memtable costs few
+// allocations and its size is known. Memory is only ever freed all at once, with the memtable. Allocate
+// must not be called concurrently, MemoryUsage can be.
+type arena struct {
+	// free is what is left of the current block.
+	free   []byte
+	blocks int
+	usage  int64
+}
+
+// allocate returns n bytes of memory.
+func (a *arena) allocate(n int) []byte {
+	if n <= len(a.free) {
+		p := a.free[:n:n]
+		a.free = a.free[n:]
+		return p
+	}
+	if n > arenaBlockSize/4 {
+		// Large allocations get a block of their own, so as not to waste what is left of the current
+		// block.
+		return a.newBlock(n)
+	}
+	a.free = a.newBlock(arenaBlockSize)
+	return a.allocate(n)
+}
+
+func (a *arena) newBlock(size int) []byte {
+	a.blocks++
+	atomic.AddInt64(&a.usage, int64(size))
+	return make([]byte, size)
+}
+
+// account adds n bytes that were allocated elsewhere on behalf of the arena to its memory usage.
+func (a *arena) account(n int) {
+	atomic.AddInt64(&a.usage, int64(n))
+}
+
+// memoryUsage returns the memory allocated by the arena.
+func (a *arena) memoryUsage() int64 {
+	return atomic.LoadInt64(&a.usage)
+}
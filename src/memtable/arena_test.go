@@ -0,0 +1,56 @@
+package memtable
+
+import "testing"
+
+func TestArena_Allocate(t *testing.T) {
+	tests := map[string]struct {
+		sizes          []int
+		expectedBlocks int
+		expectedUsage  int64
+	}{
+		"Small allocations share a block":    {[]int{10, 20, 30}, 1, arenaBlockSize},
+		"Allocations overflow into a block":  {[]int{1000, 1000, 1000, 1000, 1000}, 2, 2 * arenaBlockSize},
+		"Large allocations get a block each": {[]int{4000, 2000, 3000}, 3, 9000},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			a := &arena{}
+			for i, size := range test.sizes {
+				p := a.allocate(size)
+				if len(p) != size || cap(p) != size {
+					t.Fatalf("Expected %v bytes but got %v with capacity %v", size, len(p), cap(p))
+				}
+				for j := range p {
+					p[j] = byte(i)
+				}
+			}
+			if a.blocks != test.expectedBlocks {
+				t.Errorf("Expected %v blocks but got %v", test.expectedBlocks, a.blocks)
+			}
+			if a.memoryUsage() != test.expectedUsage {
+				t.Errorf("Expected usage %v but got %v", test.expectedUsage, a.memoryUsage())
+			}
+		})
+	}
+}
+
+func TestArena_AllocationsShouldNotOverlap(t *testing.T) {
+	a := &arena{}
+	var allocations [][]byte
+	for i := 0; i < 1000; i++ {
+		p := a.allocate(1 + i%300)
+		for j := range p {
+			p[j] = byte(i)
+		}
+		allocations = append(allocations, p)
+	}
+
+	for i, p := range allocations {
+		for _, b := range p {
+			if b != byte(i) {
+				t.Fatalf("Expected allocation %v to hold %v but got %v", i, byte(i), b)
+			}
+		}
+	}
+}
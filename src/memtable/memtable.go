@@ -0,0 +1,171 @@
+// Package memtable holds the recent updates of a database in memory, sorted by internal key, until
+// they are written to a sorted table.
+package memtable
+
+import (
+	"encoding/binary"
+
+	"comparer"
+	"dbformat"
+)
+
+// Result tells what a MemTable knows about a user key.
+type Result int
+
+const (
+	// NotFound is the result for a key that has no update in the memtable, so it has to be looked up
+	// further on.
+	NotFound Result = iota
+	// Found is the result for a key whose latest update set it to a value.
+	Found
+	// Deleted is the result for a key whose latest update deleted it.
+	Deleted
+)
+
+func (r Result) String() string {
+	switch r {
+	case NotFound:
+		return "NotFound"
+	case Found:
+		return "Found"
+	case Deleted:
+		return "Deleted"
+	}
+	return "Unknown"
+}
+
+// MemTable is a sorted set of updates, each stored as an entry of an internal key and a value:
+//
+//	entry := key length: varint32, internal key: uint8[key length],
+//	         value length: varint32, value: uint8[value length]
+//
+// Entries are kept in a skiplist and their memory comes from an arena. Add must not be called
+// concurrently, but Get and iterators can be used concurrently with Add without locking.
+type MemTable struct {
+	cmp   dbformat.InternalKeyComparator
+	arena *arena
+	list  *skiplist
+}
+
+// New creates an empty memtable whose user keys are ordered by cmp.
+func New(cmp comparer.Comparator) *MemTable {
+	m := &MemTable{cmp: dbformat.InternalKeyComparator{User: cmp}, arena: &arena{}}
+	m.list = newSkiplist(m.compareEntries, m.arena)
+	return m
+}
+
+// compareEntries orders entries, and lookup keys, by their internal keys.
+func (m *MemTable) compareEntries(a, b []byte) int {
+	return m.cmp.Compare(lengthPrefixed(a), lengthPrefixed(b))
+}
+
+// lengthPrefixed returns the slice that p starts with, behind its varint32 length.
+func lengthPrefixed(p []byte) []byte {
+	length, n := binary.Uvarint(p)
+	return p[n : n+int(length)]
+}
+
+// Add adds the update of key at seq, which sets key to value or deletes it, depending on t. The value of
+// a deletion is usually empty. Every update must have a different sequence number.
+func (m *MemTable) Add(seq dbformat.SequenceNumber, t dbformat.ValueType, key, value []byte) {
+	internalKeyLength := len(key) + 8
+	var keyLength, valueLength [binary.MaxVarintLen32]byte
+	k := binary.PutUvarint(keyLength[:], uint64(internalKeyLength))
+	v := binary.PutUvarint(valueLength[:], uint64(len(value)))
+
+	entry := m.arena.allocate(k + internalKeyLength + v + len(value))[:0]
+	entry = append(entry, keyLength[:k]...)
+	entry = dbformat.AppendInternalKey(entry, key, seq, t)
+	entry = append(entry, valueLength[:v]...)
+	entry = append(entry, value...)
+	m.list.insert(entry)
+}
+
+// Get returns the latest update of the user key of key, as of its sequence number. The value is only
+// returned for Found, and must not be changed.
+func (m *MemTable) Get(key dbformat.LookupKey) ([]byte, Result) {
+	x := m.list.findGreaterOrEqual(key.MemtableKey(), nil)
+	if x == nil {
+		return nil, NotFound
+	}
+	// x is the first entry at or after key, which is the latest update of the user key when it has the
+	// same user key.
+	internalKey := lengthPrefixed(x.key)
+	if m.cmp.User.Compare(dbformat.ExtractUserKey(internalKey), key.UserKey()) != 0 {
+		return nil, NotFound
+	}
+	parsed, _ := dbformat.ParseInternalKey(internalKey)
+	if parsed.Type == dbformat.TypeDeletion {
+		return nil, Deleted
+	}
+	return entryValue(x.key), Found
+}
+
+// entryValue returns the value of entry.
+func entryValue(entry []byte) []byte {
+	length, n := binary.Uvarint(entry)
+	return lengthPrefixed(entry[n+int(length):])
+}
+
+// ApproximateMemoryUsage returns the memory used by the memtable, which is what decides when it is
+// written to a sorted table.
+func (m *MemTable) ApproximateMemoryUsage() int64 {
+	return m.arena.memoryUsage()
+}
+
+// NewIterator returns an iterator over the entries of the memtable in internal key order. It is not
+// positioned at an entry until one of its seek methods is called.
+func (m *MemTable) NewIterator() *Iterator {
+	return &Iterator{it: skiplistIterator{list: m.list}}
+}
+
+// Iterator iterates over the entries of a MemTable. The keys it returns are internal keys. Keys and
+// values are valid for as long as the memtable is, and must not be changed.
+type Iterator struct {
+	it skiplistIterator
+	// seekKey holds the target of Seek as a length prefixed internal key.
+	seekKey []byte
+}
+
+// Valid returns whether the iterator is positioned at an entry.
+func (it *Iterator) Valid() bool {
+	return it.it.valid()
+}
+
+// Key returns the internal key of the current entry.
+func (it *Iterator) Key() []byte {
+	return lengthPrefixed(it.it.key())
+}
+
+// Value returns the value of the current entry.
+func (it *Iterator) Value() []byte {
+	return entryValue(it.it.key())
+}
+
+// Next moves to the next entry. The iterator must be valid.
+func (it *Iterator) Next() {
+	it.it.next()
+}
+
+// Prev moves to the previous entry. The iterator must be valid.
+func (it *Iterator) Prev() {
+	it.it.prev()
+}
+
+// Seek moves to the first entry whose internal key is at least internalKey.
+func (it *Iterator) Seek(internalKey []byte) {
+	var length [binary.MaxVarintLen32]byte
+	n := binary.PutUvarint(length[:], uint64(len(internalKey)))
+	it.seekKey = append(append(it.seekKey[:0], length[:n]...), internalKey...)
+	it.it.seek(it.seekKey)
+}
+
+// SeekToFirst moves to the first entry.
+func (it *Iterator) SeekToFirst() {
+	it.it.seekToFirst()
+}
+
+// SeekToLast moves to the last entry.
+func (it *Iterator) SeekToLast() {
+	it.it.seekToLast()
+}
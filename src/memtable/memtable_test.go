@@ -0,0 +1,129 @@
+package memtable
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"comparer"
+	"dbformat"
+)
+
+func TestMemTable_Get(t *testing.T) {
+	m := New(comparer.BytewiseComparator)
+	m.Add(1, dbformat.TypeValue, []byte("foo"), []byte("v1"))
+	m.Add(2, dbformat.TypeValue, []byte("bar"), []byte("b1"))
+	m.Add(3, dbformat.TypeValue, []byte("foo"), []byte("v3"))
+	m.Add(4, dbformat.TypeDeletion, []byte("foo"), nil)
+	m.Add(5, dbformat.TypeValue, []byte("foo"), []byte("v5"))
+
+	tests := map[string]struct {
+		key      string
+		seq      dbformat.SequenceNumber
+		expected Result
+		value    string
+	}{
+		"Latest value":              {"foo", 10, Found, "v5"},
+		"Value as of a sequence":    {"foo", 3, Found, "v3"},
+		"Oldest value":              {"foo", 1, Found, "v1"},
+		"Deleted as of a sequence":  {"foo", 4, Deleted, ""},
+		"Before the first update":   {"bar", 1, NotFound, ""},
+		"Other key":                 {"bar", 5, Found, "b1"},
+		"Missing key":               {"baz", 10, NotFound, ""},
+		"Prefix of an existing key": {"fo", 10, NotFound, ""},
+		"Key after every key":       {"zzz", 10, NotFound, ""},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			value, result := m.Get(dbformat.NewLookupKey([]byte(test.key), test.seq))
+			if result != test.expected || string(value) != test.value {
+				t.Errorf("Expected %v '%v' but got %v '%s'", test.expected, test.value, result, value)
+			}
+		})
+	}
+}
+
+func TestMemTable_Iterator(t *testing.T) {
+	m := New(comparer.BytewiseComparator)
+	m.Add(1, dbformat.TypeValue, []byte("b"), []byte("b1"))
+	m.Add(2, dbformat.TypeValue, []byte("a"), []byte("a2"))
+	m.Add(3, dbformat.TypeDeletion, []byte("b"), nil)
+	m.Add(4, dbformat.TypeValue, []byte("c"), []byte("c4"))
+
+	entry := func(it *Iterator) string {
+		k, _ := dbformat.ParseInternalKey(it.Key())
+		return fmt.Sprintf("%v=%s", k, it.Value())
+	}
+	expected := []string{"'a' @ 2 : Value=a2", "'b' @ 3 : Deletion=", "'b' @ 1 : Value=b1", "'c' @ 4 : Value=c4"}
+
+	it := m.NewIterator()
+	var forward []string
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		forward = append(forward, entry(it))
+	}
+	if !reflect.DeepEqual(forward, expected) {
+		t.Errorf("Expected %v but got %v", expected, forward)
+	}
+
+	var backward []string
+	for it.SeekToLast(); it.Valid(); it.Prev() {
+		backward = append([]string{entry(it)}, backward...)
+	}
+	if !reflect.DeepEqual(backward, expected) {
+		t.Errorf("Expected %v but got %v", expected, backward)
+	}
+
+	it.Seek(dbformat.AppendInternalKey(nil, []byte("b"), 2, dbformat.ValueTypeForSeek))
+	if !it.Valid() || entry(it) != expected[2] {
+		t.Errorf("Expected Seek to find %v", expected[2])
+	}
+}
+
+func TestMemTable_ShouldUseTheComparator(t *testing.T) {
+	m := New(reverseComparator{})
+	for i, key := range []string{"a", "c", "b"} {
+		m.Add(dbformat.SequenceNumber(i+1), dbformat.TypeValue, []byte(key), nil)
+	}
+
+	var keys []string
+	it := m.NewIterator()
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		keys = append(keys, string(dbformat.ExtractUserKey(it.Key())))
+	}
+	if expected := []string{"c", "b", "a"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v but got %v", expected, keys)
+	}
+}
+
+func TestMemTable_ApproximateMemoryUsage(t *testing.T) {
+	m := New(comparer.BytewiseComparator)
+	before := m.ApproximateMemoryUsage()
+
+	value := make([]byte, 100)
+	for i := 0; i < 1000; i++ {
+		m.Add(dbformat.SequenceNumber(i+1), dbformat.TypeValue, []byte(fmt.Sprintf("key%06d", i)), value)
+	}
+
+	if usage := m.ApproximateMemoryUsage() - before; usage < 1000*(100+9+8) || usage > int64(2*1000*(100+9+8+nodeSize(maxHeight))) {
+		t.Errorf("Expected the usage to be about the size of the entries but got %v", usage)
+	}
+}
+
+type reverseComparator struct{}
+
+func (reverseComparator) Compare(a, b []byte) int {
+	return -comparer.BytewiseComparator.Compare(a, b)
+}
+
+func (reverseComparator) Name() string {
+	return "test.ReverseComparator"
+}
+
+func (reverseComparator) FindShortestSeparator(start, limit []byte) []byte {
+	return append([]byte(nil), start...)
+}
+
+func (reverseComparator) FindShortSuccessor(key []byte) []byte {
+	return append([]byte(nil), key...)
+}
@@ -0,0 +1,185 @@
+package memtable
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	maxHeight = 12
+	// branching is the inverse of the probability that a node of height h also has height h+1.
+	branching = 4
+)
+
+// skiplist is a sorted set of keys, as ordered by compare.
+//
+// Inserts must be serialized, but reads can run concurrently with them without any locking: a node is
+// fully built before it is linked in, the links are read and written atomically, and nodes are never
+// removed.
+type skiplist struct {
+	compare func(a, b []byte) int
+	arena   *arena
+	rand    *rand.Rand
+
+	head *node
+	// height is the height of the highest node, read atomically.
+	height int32
+}
+
+type node struct {
+	key []byte
+	// next are the links to the next node at each level of the node, as unsafe.Pointers to nodes.
+	next []unsafe.Pointer
+}
+
+// nodeSize is roughly what a node costs besides its key.
+func nodeSize(height int) int {
+	return int(unsafe.Sizeof(node{})) + height*int(unsafe.Sizeof(unsafe.Pointer(nil)))
+}
+
+func newSkiplist(compare func(a, b []byte) int, a *arena) *skiplist {
+	return &skiplist{
+		compare: compare,
+		arena:   a,
+		rand:    rand.New(rand.NewSource(0xdeadbeef)),
+		head:    &node{next: make([]unsafe.Pointer, maxHeight)},
+		height:  1,
+	}
+}
+
+func (n *node) loadNext(level int) *node {
+	return (*node)(atomic.LoadPointer(&n.next[level]))
+}
+
+func (n *node) storeNext(level int, next *node) {
+	atomic.StorePointer(&n.next[level], unsafe.Pointer(next))
+}
+
+func (s *skiplist) loadHeight() int {
+	return int(atomic.LoadInt32(&s.height))
+}
+
+func (s *skiplist) randomHeight() int {
+	height := 1
+	for height < maxHeight && s.rand.Intn(branching) == 0 {
+		height++
+	}
+	return height
+}
+
+// findGreaterOrEqual returns the first node whose key is at least key, or nil when there is none. When
+// prev is not nil, it is set to the last node before key at each level.
+func (s *skiplist) findGreaterOrEqual(key []byte, prev []*node) *node {
+	x := s.head
+	for level := s.loadHeight() - 1; ; level-- {
+		next := x.loadNext(level)
+		for next != nil && s.compare(next.key, key) < 0 {
+			x, next = next, next.loadNext(level)
+		}
+		if prev != nil {
+			prev[level] = x
+		}
+		if level == 0 {
+			return next
+		}
+	}
+}
+
+// findLessThan returns the last node whose key is less than key, or the head when there is none.
+func (s *skiplist) findLessThan(key []byte) *node {
+	x := s.head
+	for level := s.loadHeight() - 1; ; level-- {
+		next := x.loadNext(level)
+		for next != nil && s.compare(next.key, key) < 0 {
+			x, next = next, next.loadNext(level)
+		}
+		if level == 0 {
+			return x
+		}
+	}
+}
+
+// findLast returns the last node, or the head when the list is empty.
+func (s *skiplist) findLast() *node {
+	x := s.head
+	for level := s.loadHeight() - 1; ; level-- {
+		for next := x.loadNext(level); next != nil; next = x.loadNext(level) {
+			x = next
+		}
+		if level == 0 {
+			return x
+		}
+	}
+}
+
+// insert adds key, which must not be in the list yet. The list keeps key, which must not change.
+func (s *skiplist) insert(key []byte) {
+	var prev [maxHeight]*node
+	s.findGreaterOrEqual(key, prev[:])
+
+	height := s.randomHeight()
+	if listHeight := s.loadHeight(); height > listHeight {
+		for level := listHeight; level < height; level++ {
+			prev[level] = s.head
+		}
+		// Readers that see the new height before the node is linked in find nil links in the head at
+		// the new levels, and just move down a level.
+		atomic.StoreInt32(&s.height, int32(height))
+	}
+
+	x := &node{key: key, next: make([]unsafe.Pointer, height)}
+	s.arena.account(nodeSize(height))
+	for level := 0; level < height; level++ {
+		x.storeNext(level, prev[level].loadNext(level))
+		prev[level].storeNext(level, x)
+	}
+}
+
+// contains returns whether key is in the list.
+func (s *skiplist) contains(key []byte) bool {
+	x := s.findGreaterOrEqual(key, nil)
+	return x != nil && s.compare(x.key, key) == 0
+}
+
+// skiplistIterator iterates over the keys of a skiplist. It sees the keys inserted while it iterates
+// when it gets to them.
+type skiplistIterator struct {
+	list *skiplist
+	node *node
+}
+
+func (it *skiplistIterator) valid() bool {
+	return it.node != nil
+}
+
+func (it *skiplistIterator) key() []byte {
+	return it.node.key
+}
+
+func (it *skiplistIterator) next() {
+	it.node = it.node.loadNext(0)
+}
+
+func (it *skiplistIterator) prev() {
+	// There are no back links, so search for the last node before the current one.
+	it.node = it.list.findLessThan(it.node.key)
+	if it.node == it.list.head {
+		it.node = nil
+	}
+}
+
+func (it *skiplistIterator) seek(key []byte) {
+	it.node = it.list.findGreaterOrEqual(key, nil)
+}
+
+func (it *skiplistIterator) seekToFirst() {
+	it.node = it.list.head.loadNext(0)
+}
+
+func (it *skiplistIterator) seekToLast() {
+	it.node = it.list.findLast()
+	if it.node == it.list.head {
+		it.node = nil
+	}
+}
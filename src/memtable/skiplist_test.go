@@ -0,0 +1,137 @@
+package memtable
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func uint64Key(n uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, n)
+	return key
+}
+
+func TestSkiplist_Empty(t *testing.T) {
+	s := newSkiplist(bytes.Compare, &arena{})
+	if s.contains(uint64Key(10)) {
+		t.Error("Expected an empty list not to contain the key but it did")
+	}
+
+	it := skiplistIterator{list: s}
+	for name, seek := range map[string]func(){
+		"SeekToFirst": it.seekToFirst,
+		"SeekToLast":  it.seekToLast,
+		"Seek":        func() { it.seek(uint64Key(100)) },
+	} {
+		seek()
+		if it.valid() {
+			t.Errorf("Expected the iterator to be invalid after %v but it was not", name)
+		}
+	}
+}
+
+func TestSkiplist_InsertAndLookup(t *testing.T) {
+	const n, keyRange = 2000, 5000
+	r := rand.New(rand.NewSource(301))
+	s := newSkiplist(bytes.Compare, &arena{})
+	inserted := map[uint64]bool{}
+	for i := 0; i < n; i++ {
+		k := uint64(r.Intn(keyRange))
+		if !inserted[k] {
+			inserted[k] = true
+			s.insert(uint64Key(k))
+		}
+	}
+	var keys []uint64
+	for k := range inserted {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	for k := uint64(0); k < keyRange; k++ {
+		if s.contains(uint64Key(k)) != inserted[k] {
+			t.Fatalf("Expected contains(%v) to be %v but was not", k, inserted[k])
+		}
+	}
+
+	it := skiplistIterator{list: s}
+	it.seekToFirst()
+	for _, k := range keys {
+		if !it.valid() || !bytes.Equal(it.key(), uint64Key(k)) {
+			t.Fatalf("Expected key %v when iterating forward", k)
+		}
+		it.next()
+	}
+	if it.valid() {
+		t.Error("Expected the iterator to be invalid after the last key but it was not")
+	}
+
+	it.seekToLast()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !it.valid() || !bytes.Equal(it.key(), uint64Key(keys[i])) {
+			t.Fatalf("Expected key %v when iterating backward", keys[i])
+		}
+		it.prev()
+	}
+	if it.valid() {
+		t.Error("Expected the iterator to be invalid before the first key but it was not")
+	}
+
+	for i := 0; i < 100; i++ {
+		target := uint64(r.Intn(keyRange))
+		it.seek(uint64Key(target))
+		j := sort.Search(len(keys), func(j int) bool { return keys[j] >= target })
+		if j == len(keys) {
+			if it.valid() {
+				t.Errorf("Expected Seek(%v) to be invalid but it was not", target)
+			}
+		} else if !it.valid() || !bytes.Equal(it.key(), uint64Key(keys[j])) {
+			t.Errorf("Expected Seek(%v) to find %v", target, keys[j])
+		}
+	}
+}
+
+// Readers running concurrently with a writer should always see the keys in order, and every key that was
+// inserted before they started.
+func TestSkiplist_ConcurrentReadsShouldSeeConsistentLists(t *testing.T) {
+	const n = 5000
+	s := newSkiplist(bytes.Compare, &arena{})
+	for k := uint64(0); k < n; k += 2 {
+		s.insert(uint64Key(k))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := 0; round < 10; round++ {
+				it := skiplistIterator{list: s}
+				var previous []byte
+				seen := 0
+				for it.seekToFirst(); it.valid(); it.next() {
+					if previous != nil && bytes.Compare(previous, it.key()) >= 0 {
+						t.Errorf("Expected keys in order but got %v after %v", it.key(), previous)
+						return
+					}
+					if binary.BigEndian.Uint64(it.key())%2 == 0 {
+						seen++
+					}
+					previous = it.key()
+				}
+				if seen != n/2 {
+					t.Errorf("Expected the %v keys inserted before the readers started but got %v", n/2, seen)
+					return
+				}
+			}
+		}()
+	}
+	for k := uint64(1); k < n; k += 2 {
+		s.insert(uint64Key(k))
+	}
+	wg.Wait()
+}
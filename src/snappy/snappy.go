@@ -0,0 +1,252 @@
+// Package snappy implements the snappy block format, as used by LevelDB to compress table blocks.
+//
+// The format is described in https://github.com/google/snappy/blob/master/format_description.txt.
+// Only the block format is implemented, not the framing format used for streams.
+package snappy
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	tagLiteral = 0x00
+	tagCopy1   = 0x01
+	tagCopy2   = 0x02
+	tagCopy4   = 0x03
+
+	// maxBlockSize is the size of the chunks that Encode compresses independently of each other, which
+	// keeps every copy offset within 16 bits.
+	maxBlockSize = 65536
+
+	// maxExpansion bounds the ratio of the decoded length to the encoded length.
+	maxExpansion = 22
+
+	// minMatch is the shortest match that Encode looks for.
+	minMatch = 4
+
+	tableBits = 14
+	tableSize = 1 << tableBits
+)
+
+var (
+	// ErrCorrupt is returned when the input is not valid snappy data.
+	ErrCorrupt = errors.New("snappy: corrupt input")
+	// ErrTooLarge is returned when the decoded length does not fit in an int.
+	ErrTooLarge = errors.New("snappy: decoded block is too large")
+)
+
+// MaxEncodedLen returns the maximum length of the encoding of n bytes.
+func MaxEncodedLen(n int) int {
+	return 32 + n + n/6
+}
+
+// Encode returns the encoding of src. It uses dst when it is large enough.
+func Encode(dst, src []byte) []byte {
+	if n := MaxEncodedLen(len(src)); cap(dst) < n {
+		dst = make([]byte, n)
+	} else {
+		dst = dst[:n]
+	}
+
+	d := binary.PutUvarint(dst, uint64(len(src)))
+	for len(src) > 0 {
+		p := src
+		if len(p) > maxBlockSize {
+			p = p[:maxBlockSize]
+		}
+		d += encodeBlock(dst[d:], p)
+		src = src[len(p):]
+	}
+	return dst[:d]
+}
+
+// encodeBlock greedily replaces every run of at least minMatch bytes seen before in src with a copy.
+// Earlier positions are found through a hash table of the four bytes starting at each position.
+func encodeBlock(dst, src []byte) int {
+	var table [tableSize]int32 // position + 1 of the last occurrence of each hash, 0 if none
+	var d, nextEmit int
+
+	for s := 0; s+minMatch <= len(src); {
+		h := hash(load32(src, s))
+		candidate := int(table[h]) - 1
+		table[h] = int32(s + 1)
+		if candidate < 0 || load32(src, candidate) != load32(src, s) {
+			s++
+			continue
+		}
+
+		length := minMatch
+		for s+length < len(src) && src[candidate+length] == src[s+length] {
+			length++
+		}
+		d += emitLiteral(dst[d:], src[nextEmit:s])
+		d += emitCopy(dst[d:], s-candidate, length)
+		s += length
+		nextEmit = s
+	}
+
+	return d + emitLiteral(dst[d:], src[nextEmit:])
+}
+
+func load32(b []byte, i int) uint32 {
+	return binary.LittleEndian.Uint32(b[i : i+4])
+}
+
+func hash(u uint32) uint32 {
+	return (u * 0x1e35a7bd) >> (32 - tableBits)
+}
+
+func emitLiteral(dst, lit []byte) int {
+	if len(lit) == 0 {
+		return 0
+	}
+
+	var i int
+	switch n := uint(len(lit) - 1); {
+	case n < 60:
+		dst[0] = byte(n)<<2 | tagLiteral
+		i = 1
+	case n < 1<<8:
+		dst[0] = 60<<2 | tagLiteral
+		dst[1] = byte(n)
+		i = 2
+	case n < 1<<16:
+		dst[0] = 61<<2 | tagLiteral
+		binary.LittleEndian.PutUint16(dst[1:], uint16(n))
+		i = 3
+	case n < 1<<24:
+		dst[0] = 62<<2 | tagLiteral
+		dst[1], dst[2], dst[3] = byte(n), byte(n>>8), byte(n>>16)
+		i = 4
+	default:
+		dst[0] = 63<<2 | tagLiteral
+		binary.LittleEndian.PutUint32(dst[1:], uint32(n))
+		i = 5
+	}
+	return i + copy(dst[i:], lit)
+}
+
+// emitCopy writes copies of at most 64 bytes, using the one byte offset form where it fits.
+func emitCopy(dst []byte, offset, length int) int {
+	var i int
+	for length >= 68 {
+		i += emitCopy2(dst[i:], offset, 64)
+		length -= 64
+	}
+	if length > 64 {
+		// Leave at least 4 bytes, so that the rest can still use the one byte offset form.
+		i += emitCopy2(dst[i:], offset, 60)
+		length -= 60
+	}
+	if length >= 12 || offset >= 2048 {
+		return i + emitCopy2(dst[i:], offset, length)
+	}
+	dst[i] = byte(offset>>8)<<5 | byte(length-4)<<2 | tagCopy1
+	dst[i+1] = byte(offset)
+	return i + 2
+}
+
+func emitCopy2(dst []byte, offset, length int) int {
+	dst[0] = byte(length-1)<<2 | tagCopy2
+	binary.LittleEndian.PutUint16(dst[1:], uint16(offset))
+	return 3
+}
+
+// DecodedLen returns the length of the decoding of src.
+func DecodedLen(src []byte) (int, error) {
+	n, _, err := decodedLen(src)
+	return n, err
+}
+
+func decodedLen(src []byte) (n, headerLen int, err error) {
+	v, headerLen := binary.Uvarint(src)
+	if headerLen <= 0 || v > 0xffffffff {
+		return 0, 0, ErrCorrupt
+	}
+	if uint64(int(v)) != v {
+		return 0, 0, ErrTooLarge
+	}
+	// No element expands by more than maxExpansion: a 3 byte copy produces at most 64 bytes. A longer
+	// decoding cannot come from src, so it is rejected before anything is allocated for it.
+	if v > uint64(len(src)-headerLen)*maxExpansion {
+		return 0, 0, ErrCorrupt
+	}
+	return int(v), headerLen, nil
+}
+
+// Decode returns the decoding of src. It uses dst when it is large enough.
+func Decode(dst, src []byte) ([]byte, error) {
+	n, s, err := decodedLen(src)
+	if err != nil {
+		return nil, err
+	}
+	if cap(dst) < n {
+		dst = make([]byte, n)
+	} else {
+		dst = dst[:n]
+	}
+
+	var d, offset, length int
+	for s < len(src) {
+		tag := src[s]
+		switch tag & 0x03 {
+		case tagLiteral:
+			x := uint(tag >> 2)
+			s++
+			if x >= 60 {
+				size := int(x - 59)
+				if s+size > len(src) {
+					return nil, ErrCorrupt
+				}
+				x = 0
+				for i := size - 1; i >= 0; i-- {
+					x = x<<8 | uint(src[s+i])
+				}
+				s += size
+			}
+			length = int(x) + 1
+			if length <= 0 || length > n-d || length > len(src)-s {
+				return nil, ErrCorrupt
+			}
+			copy(dst[d:], src[s:s+length])
+			d += length
+			s += length
+			continue
+		case tagCopy1:
+			if s+2 > len(src) {
+				return nil, ErrCorrupt
+			}
+			length = 4 + int(tag>>2)&0x07
+			offset = int(tag&0xe0)<<3 | int(src[s+1])
+			s += 2
+		case tagCopy2:
+			if s+3 > len(src) {
+				return nil, ErrCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[s+1:]))
+			s += 3
+		case tagCopy4:
+			if s+5 > len(src) {
+				return nil, ErrCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[s+1:]))
+			s += 5
+		}
+
+		if offset <= 0 || offset > d || length > n-d {
+			return nil, ErrCorrupt
+		}
+		// The copy may overlap the bytes it produces, so it has to go byte by byte.
+		for end := d + length; d < end; d++ {
+			dst[d] = dst[d-offset]
+		}
+	}
+
+	if d != n {
+		return nil, ErrCorrupt
+	}
+	return dst, nil
+}
@@ -0,0 +1,107 @@
+package snappy
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, 200000)
+	rng.Read(random)
+	repetitive := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 5000)
+
+	tests := map[string][]byte{
+		"Empty":                        {},
+		"Single byte":                  []byte("a"),
+		"Short literal":                []byte("hello world"),
+		"Long run of one byte":         bytes.Repeat([]byte{'a'}, 100000),
+		"Repetitive text over 64KB":    repetitive,
+		"Random bytes":                 random,
+		"Random bytes with long match": append(append([]byte(nil), random[:5000]...), random[:5000]...),
+	}
+
+	for testName, input := range tests {
+		t.Run(testName, func(t *testing.T) {
+			encoded := Encode(nil, input)
+			if len(encoded) > MaxEncodedLen(len(input)) {
+				t.Errorf("Expected at most %v bytes but got %v", MaxEncodedLen(len(input)), len(encoded))
+			}
+
+			decoded, err := Decode(nil, encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded, input) {
+				t.Fatal("Expected contents to be equal but was not")
+			}
+		})
+	}
+}
+
+func TestEncode_ShouldCompressRepetitiveInput(t *testing.T) {
+	input := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 5000)
+	if encoded := Encode(nil, input); len(encoded) > len(input)/10 {
+		t.Errorf("Expected %v bytes to compress to less than %v bytes but got %v", len(input), len(input)/10, len(encoded))
+	}
+}
+
+func TestDecode_KnownEncodings(t *testing.T) {
+	tests := map[string]struct {
+		encoded  string
+		expected string
+	}{
+		"Empty":                    {encoded: "\x00", expected: ""},
+		"Literal":                  {encoded: "\x03\x08abc", expected: "abc"},
+		"Copy with 1 byte offset":  {encoded: "\x08\x0cabcd\x01\x04", expected: "abcdabcd"},
+		"Copy with 2 byte offset":  {encoded: "\x06\x04ab\x0e\x02\x00", expected: "ababab"},
+		"Copy with 4 byte offset":  {encoded: "\x06\x04ab\x0f\x02\x00\x00\x00", expected: "ababab"},
+		"Literal with 1 byte size": {encoded: "\x3d\xf0\x3c" + string(bytes.Repeat([]byte{'x'}, 61)), expected: string(bytes.Repeat([]byte{'x'}, 61))},
+		"Longest copies":           {encoded: "\x81\xf4\x03\x00a" + strings.Repeat("\xfe\x01\x00", 1000), expected: strings.Repeat("a", 64001)},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			decoded, err := Decode(nil, []byte(test.encoded))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(decoded) != test.expected {
+				t.Errorf("Expected '%v' but got '%s'", test.expected, decoded)
+			}
+		})
+	}
+}
+
+func TestDecode_Corrupt(t *testing.T) {
+	tests := map[string]string{
+		"Missing length":             "",
+		"Literal longer than input":  "\x03\x08ab",
+		"Literal longer than length": "\x02\x08abc",
+		"Copy before the start":      "\x08\x0cabcd\x01\x05",
+		"Zero offset":                "\x08\x0cabcd\x01\x00",
+		"Truncated copy":             "\x08\x0cabcd\x02\x04",
+		"Shorter than length":        "\x04\x08abc",
+		"Length above 32 bits":       "\xff\xff\xff\xff\xff\xff\xff\xff\x3f\x00",
+		"Length beyond expansion":    "\xff\xff\x03\x00",
+	}
+
+	for testName, encoded := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if _, err := Decode(nil, []byte(encoded)); err != ErrCorrupt {
+				t.Errorf("Expected '%v' but got '%v'", ErrCorrupt, err)
+			}
+		})
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	input := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 1000)
+	dst := make([]byte, MaxEncodedLen(len(input)))
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		Encode(dst, input)
+	}
+}
@@ -0,0 +1,215 @@
+package table
+
+import (
+	"encoding/binary"
+
+	"cache"
+	"comparer"
+)
+
+// block is the parsed contents of a block, see the package documentation for the format.
+type block struct {
+	data []byte
+	// restartsOffset is where the restart points start in data, and so where the entries end.
+	restartsOffset int
+	numRestarts    int
+
+	// fileNumber and offset say where the block came from, for corruption errors.
+	fileNumber uint64
+	offset     uint64
+}
+
+// newBlock parses contents, the contents of the block at offset in table fileNumber.
+func newBlock(contents []byte, fileNumber, offset uint64) (*block, error) {
+	b := &block{data: contents, fileNumber: fileNumber, offset: offset}
+	if len(contents) < 4 {
+		return nil, b.corruption("block contents too small")
+	}
+	b.numRestarts = int(binary.LittleEndian.Uint32(contents[len(contents)-4:]))
+	maxRestarts := (len(contents) - 4) / 4
+	if b.numRestarts > maxRestarts {
+		return nil, b.corruption("bad restart count in block")
+	}
+	b.restartsOffset = len(contents) - 4 - 4*b.numRestarts
+	return b, nil
+}
+
+func (b *block) corruption(reason string) error {
+	return CorruptionError{FileNumber: b.fileNumber, Offset: b.offset, Reason: reason}
+}
+
+func (b *block) restartPoint(i int) int {
+	return int(binary.LittleEndian.Uint32(b.data[b.restartsOffset+4*i:]))
+}
+
+func (b *block) newIterator(cmp comparer.Comparator) *blockIterator {
+	return &blockIterator{block: b, cmp: cmp, current: b.restartsOffset, restartIndex: b.numRestarts}
+}
+
+// blockIterator iterates over the entries of a block.
+type blockIterator struct {
+	block *block
+	cmp   comparer.Comparator
+
+	// current is the offset of the current entry, or restartsOffset when the iterator is not valid.
+	current int
+	// restartIndex is the index of the restart point that current comes after.
+	restartIndex int
+	// next is the offset of the entry after the current one.
+	next  int
+	key   []byte
+	value []byte
+	err   error
+
+	// handle, when not nil, is the handle to the block in blockCache, which Release gives back.
+	blockCache cache.Cache
+	handle     *cache.Handle
+}
+
+func (it *blockIterator) Valid() bool {
+	return it.err == nil && it.current < it.block.restartsOffset
+}
+
+func (it *blockIterator) Key() []byte {
+	return it.key
+}
+
+func (it *blockIterator) Value() []byte {
+	return it.value
+}
+
+func (it *blockIterator) Err() error {
+	return it.err
+}
+
+func (it *blockIterator) Release() {
+	if it.handle != nil {
+		it.blockCache.Release(it.handle)
+		it.handle = nil
+	}
+	it.invalidate()
+}
+
+func (it *blockIterator) invalidate() {
+	it.current = it.block.restartsOffset
+	it.restartIndex = it.block.numRestarts
+}
+
+func (it *blockIterator) seekToRestartPoint(i int) {
+	it.key = it.key[:0]
+	it.restartIndex = i
+	it.next = it.block.restartPoint(i)
+}
+
+// parseNextKey moves to the entry at it.next. It returns false at the end of the block or when the
+// entry is corrupt.
+func (it *blockIterator) parseNextKey() bool {
+	it.current = it.next
+	if it.current >= it.block.restartsOffset {
+		it.invalidate()
+		return false
+	}
+
+	p := it.block.data[it.current:it.block.restartsOffset]
+	var lengths [3]uint64
+	headerLength := 0
+	for i := range lengths {
+		length, n := binary.Uvarint(p[headerLength:])
+		if n <= 0 {
+			return it.corrupt()
+		}
+		lengths[i] = length
+		headerLength += n
+	}
+	shared, nonShared, valueLength := lengths[0], lengths[1], lengths[2]
+	p = p[headerLength:]
+	if shared > uint64(len(it.key)) || nonShared > uint64(len(p)) || valueLength > uint64(len(p))-nonShared {
+		return it.corrupt()
+	}
+
+	it.key = append(it.key[:shared], p[:nonShared]...)
+	it.value = p[nonShared : nonShared+valueLength]
+	it.next = it.current + headerLength + int(nonShared+valueLength)
+	for it.restartIndex+1 < it.block.numRestarts && it.block.restartPoint(it.restartIndex+1) <= it.current {
+		it.restartIndex++
+	}
+	return true
+}
+
+func (it *blockIterator) corrupt() bool {
+	it.err = it.block.corruption("bad entry in block")
+	it.invalidate()
+	return false
+}
+
+func (it *blockIterator) Next() {
+	it.parseNextKey()
+}
+
+func (it *blockIterator) Prev() {
+	// Back up to the last restart point before the current entry, and scan forward from there to the
+	// entry before it.
+	original := it.current
+	for it.block.restartPoint(it.restartIndex) >= original {
+		if it.restartIndex == 0 {
+			it.invalidate()
+			return
+		}
+		it.restartIndex--
+	}
+	it.seekToRestartPoint(it.restartIndex)
+	for it.parseNextKey() && it.next < original {
+	}
+}
+
+// Seek moves to the first entry whose key is at least target.
+func (it *blockIterator) Seek(target []byte) {
+	if it.block.numRestarts == 0 {
+		it.invalidate()
+		return
+	}
+
+	// Binary search for the last restart point whose key is less than target.
+	left, right := 0, it.block.numRestarts-1
+	for left < right {
+		mid := (left + right + 1) / 2
+		it.seekToRestartPoint(mid)
+		if !it.parseNextKey() {
+			if it.err == nil {
+				it.corrupt()
+			}
+			return
+		}
+		if it.cmp.Compare(it.key, target) < 0 {
+			left = mid
+		} else {
+			right = mid - 1
+		}
+	}
+
+	it.seekToRestartPoint(left)
+	for it.parseNextKey() {
+		if it.cmp.Compare(it.key, target) >= 0 {
+			return
+		}
+	}
+}
+
+func (it *blockIterator) SeekToFirst() {
+	if it.block.numRestarts == 0 {
+		it.invalidate()
+		return
+	}
+	it.seekToRestartPoint(0)
+	it.parseNextKey()
+}
+
+func (it *blockIterator) SeekToLast() {
+	if it.block.numRestarts == 0 {
+		it.invalidate()
+		return
+	}
+	it.seekToRestartPoint(it.block.numRestarts - 1)
+	for it.parseNextKey() && it.next < it.block.restartsOffset {
+	}
+}
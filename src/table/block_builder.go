@@ -0,0 +1,73 @@
+package table
+
+import "encoding/binary"
+
+// blockBuilder builds the contents of a block, see the package documentation for the format. Keys must
+// be added in increasing order.
+type blockBuilder struct {
+	restartInterval int
+
+	buf      []byte
+	restarts []uint32
+	// counter is the number of entries since the last restart point.
+	counter int
+	lastKey []byte
+}
+
+func newBlockBuilder(restartInterval int) *blockBuilder {
+	return &blockBuilder{restartInterval: restartInterval, restarts: []uint32{0}}
+}
+
+// reset empties the builder for the next block.
+func (b *blockBuilder) reset() {
+	b.buf = b.buf[:0]
+	b.restarts = append(b.restarts[:0], 0)
+	b.counter = 0
+	b.lastKey = b.lastKey[:0]
+}
+
+// add appends an entry. key must be greater than the key added before it.
+func (b *blockBuilder) add(key, value []byte) {
+	shared := 0
+	if b.counter < b.restartInterval {
+		for shared < len(key) && shared < len(b.lastKey) && key[shared] == b.lastKey[shared] {
+			shared++
+		}
+	} else {
+		b.restarts = append(b.restarts, uint32(len(b.buf)))
+		b.counter = 0
+	}
+
+	var lengths [3 * binary.MaxVarintLen32]byte
+	n := binary.PutUvarint(lengths[:], uint64(shared))
+	n += binary.PutUvarint(lengths[n:], uint64(len(key)-shared))
+	n += binary.PutUvarint(lengths[n:], uint64(len(value)))
+	b.buf = append(b.buf, lengths[:n]...)
+	b.buf = append(b.buf, key[shared:]...)
+	b.buf = append(b.buf, value...)
+
+	b.lastKey = append(b.lastKey[:0], key...)
+	b.counter++
+}
+
+// finish appends the restart points and returns the contents of the block, which are valid until the
+// builder is reset.
+func (b *blockBuilder) finish() []byte {
+	var fixed [4]byte
+	for _, restart := range b.restarts {
+		binary.LittleEndian.PutUint32(fixed[:], restart)
+		b.buf = append(b.buf, fixed[:]...)
+	}
+	binary.LittleEndian.PutUint32(fixed[:], uint32(len(b.restarts)))
+	b.buf = append(b.buf, fixed[:]...)
+	return b.buf
+}
+
+// currentSizeEstimate returns the size of the block if it were finished now.
+func (b *blockBuilder) currentSizeEstimate() int {
+	return len(b.buf) + 4*len(b.restarts) + 4
+}
+
+func (b *blockBuilder) empty() bool {
+	return len(b.buf) == 0
+}
@@ -0,0 +1,52 @@
+package table
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBlockBuilder_ShouldPrefixCompressKeysBetweenRestarts(t *testing.T) {
+	b := newBlockBuilder(2)
+	b.add([]byte("apple"), []byte("1"))
+	b.add([]byte("applet"), []byte("2"))
+	b.add([]byte("apply"), []byte("3"))
+	estimate := b.currentSizeEstimate()
+
+	expected := []byte{
+		0, 5, 1, 'a', 'p', 'p', 'l', 'e', '1', // offset 0, restart
+		5, 1, 1, 't', '2', // offset 9
+		0, 5, 1, 'a', 'p', 'p', 'l', 'y', '3', // offset 14, restart
+		0, 0, 0, 0, 14, 0, 0, 0, // restarts
+		2, 0, 0, 0, // number of restarts
+	}
+	contents := b.finish()
+	if !bytes.Equal(contents, expected) {
+		t.Errorf("Expected %v but got %v", expected, contents)
+	}
+	if estimate != len(expected) {
+		t.Errorf("Expected the estimate to be %v but got %v", len(expected), estimate)
+	}
+}
+
+func TestBlockBuilder_Reset(t *testing.T) {
+	b := newBlockBuilder(16)
+	b.add([]byte("key"), []byte("value"))
+	b.finish()
+	b.reset()
+
+	if !b.empty() {
+		t.Error("Expected the builder to be empty but it was not")
+	}
+	b.add([]byte("key"), []byte("value"))
+	expected := []byte{0, 3, 5, 'k', 'e', 'y', 'v', 'a', 'l', 'u', 'e', 0, 0, 0, 0, 1, 0, 0, 0}
+	if contents := b.finish(); !bytes.Equal(contents, expected) {
+		t.Errorf("Expected %v but got %v", expected, contents)
+	}
+}
+
+func TestBlockBuilder_EmptyBlock(t *testing.T) {
+	expected := []byte{0, 0, 0, 0, 1, 0, 0, 0}
+	if contents := newBlockBuilder(16).finish(); !bytes.Equal(contents, expected) {
+		t.Errorf("Expected %v but got %v", expected, contents)
+	}
+}
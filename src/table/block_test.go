@@ -0,0 +1,139 @@
+package table
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"comparer"
+)
+
+func buildBlock(restartInterval int, keys []string) *block {
+	bb := newBlockBuilder(restartInterval)
+	for _, key := range keys {
+		bb.add([]byte(key), []byte("v-"+key))
+	}
+	b, err := newBlock(append([]byte(nil), bb.finish()...), 1, 0)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func testKeys(n int) []string {
+	var keys []string
+	for i := 0; i < n; i++ {
+		keys = append(keys, fmt.Sprintf("key%04d", 2*i))
+	}
+	return keys
+}
+
+func collectForward(it Iterator) []string {
+	var keys []string
+	for ; it.Valid(); it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	return keys
+}
+
+func collectBackward(it Iterator) []string {
+	var keys []string
+	for ; it.Valid(); it.Prev() {
+		keys = append([]string{string(it.Key())}, keys...)
+	}
+	return keys
+}
+
+func TestBlockIterator(t *testing.T) {
+	for _, restartInterval := range []int{1, 2, 16} {
+		for _, n := range []int{0, 1, 2, 17, 100} {
+			t.Run(fmt.Sprintf("%v keys restart interval %v", n, restartInterval), func(t *testing.T) {
+				keys := testKeys(n)
+				it := buildBlock(restartInterval, keys).newIterator(comparer.BytewiseComparator)
+
+				it.SeekToFirst()
+				if actual := collectForward(it); !reflect.DeepEqual(actual, keys) {
+					t.Errorf("Expected %v forward but got %v", keys, actual)
+				}
+				it.SeekToLast()
+				if actual := collectBackward(it); !reflect.DeepEqual(actual, keys) {
+					t.Errorf("Expected %v backward but got %v", keys, actual)
+				}
+
+				for i := 0; i <= 2*n; i++ {
+					it.Seek([]byte(fmt.Sprintf("key%04d", i)))
+					if (i+1)/2 == n {
+						if it.Valid() {
+							t.Errorf("Expected Seek past the last key to be invalid but got %s", it.Key())
+						}
+						continue
+					}
+					expected := keys[(i+1)/2]
+					if !it.Valid() || string(it.Key()) != expected || string(it.Value()) != "v-"+expected {
+						t.Errorf("Expected Seek(%v) to find %v", i, expected)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestNewBlock_ShouldRejectBadContents(t *testing.T) {
+	tests := map[string][]byte{
+		"Too small":         {1, 0, 0},
+		"Too many restarts": {0, 0, 0, 0, 2, 0, 0, 0},
+	}
+
+	for testName, contents := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := newBlock(contents, 7, 100)
+			if e, ok := err.(CorruptionError); !ok || e.FileNumber != 7 || e.Offset != 100 {
+				t.Errorf("Expected a corruption error of table 7 at 100 but got '%v'", err)
+			}
+		})
+	}
+}
+
+func TestBlockIterator_ShouldReportCorruptEntries(t *testing.T) {
+	bb := newBlockBuilder(16)
+	bb.add([]byte("a"), []byte("1"))
+	bb.add([]byte("b"), []byte("2"))
+	contents := bb.finish()
+	// Make the value of the second entry run into the restart points.
+	contents[7] = 200
+
+	b, err := newBlock(contents, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := b.newIterator(comparer.BytewiseComparator)
+	it.SeekToFirst()
+	if !it.Valid() {
+		t.Fatal("Expected the first entry to be valid but it was not")
+	}
+	it.Next()
+	if _, ok := it.Err().(CorruptionError); it.Valid() || !ok {
+		t.Errorf("Expected a corruption error but got '%v'", it.Err())
+	}
+}
+
+func TestBlockIterator_ShouldReportRestartPointsThatShareKeys(t *testing.T) {
+	bb := newBlockBuilder(1)
+	bb.add([]byte("a"), nil)
+	bb.add([]byte("b"), nil)
+	contents := bb.finish()
+	// Make the second restart point the entry of "b" with one shared byte.
+	binary.LittleEndian.PutUint32(contents[len(contents)-8:], 0)
+	contents[0] = 1
+
+	b, err := newBlock(contents, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := b.newIterator(comparer.BytewiseComparator)
+	it.Seek([]byte("b"))
+	if _, ok := it.Err().(CorruptionError); it.Valid() || !ok {
+		t.Errorf("Expected a corruption error but got '%v'", it.Err())
+	}
+}
@@ -0,0 +1,189 @@
+package table
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"crc32c"
+	"snappy"
+)
+
+var errorBuilderClosed = errors.New("table: builder is finished or abandoned")
+var errorKeyOutOfOrder = errors.New("table: keys must be added in increasing order")
+
+// Builder writes a table to an io.Writer, one key-value pair at a time. The keys must be added in the
+// order of Options.Comparer. Nothing is synced, that is up to the caller once Finish returns.
+//
+// An error writing to dest is sticky: it is returned by every later call.
+type Builder struct {
+	dest io.Writer
+	opts Options
+
+	// offset is where the next block starts in dest.
+	offset     uint64
+	err        error
+	closed     bool
+	numEntries int
+	lastKey    []byte
+
+	data   *blockBuilder
+	index  *blockBuilder
+	filter *filterBlockBuilder
+
+	// pendingHandle is the handle of the last data block written, which is only added to the index
+	// with the first key of the next block, so that its index key can be shortened to a separator of
+	// the two blocks.
+	pendingHandle     BlockHandle
+	pendingIndexEntry bool
+
+	compressed []byte
+	trailer    [blockTrailerSize]byte
+}
+
+// NewBuilder creates a builder that writes a table to dest, which must be empty.
+func NewBuilder(dest io.Writer, opts Options) *Builder {
+	opts = opts.withDefaults()
+	b := &Builder{
+		dest:  dest,
+		opts:  opts,
+		data:  newBlockBuilder(opts.BlockRestartInterval),
+		index: newBlockBuilder(1),
+	}
+	if opts.FilterPolicy != nil {
+		b.filter = newFilterBlockBuilder(opts.FilterPolicy)
+		b.filter.startBlock(0)
+	}
+	return b
+}
+
+// Add adds the pair of key and value. key must come after every key added before it.
+func (b *Builder) Add(key, value []byte) error {
+	switch {
+	case b.closed:
+		return errorBuilderClosed
+	case b.err != nil:
+		return b.err
+	case b.numEntries > 0 && b.opts.Comparer.Compare(key, b.lastKey) <= 0:
+		return errorKeyOutOfOrder
+	}
+
+	if b.pendingIndexEntry {
+		b.addIndexEntry(b.opts.Comparer.FindShortestSeparator(b.lastKey, key))
+	}
+
+	if b.filter != nil {
+		b.filter.addKey(key)
+	}
+	b.data.add(key, value)
+	b.lastKey = append(b.lastKey[:0], key...)
+	b.numEntries++
+	if b.data.currentSizeEstimate() >= b.opts.BlockSize {
+		return b.Flush()
+	}
+	return nil
+}
+
+func (b *Builder) addIndexEntry(key []byte) {
+	var handle [maxBlockHandleLength]byte
+	b.index.add(key, b.pendingHandle.AppendTo(handle[:0]))
+	b.pendingIndexEntry = false
+}
+
+// Flush ends the current data block, so that the next key starts a new one. It is rarely needed, as
+// blocks end by themselves once they reach Options.BlockSize.
+func (b *Builder) Flush() error {
+	switch {
+	case b.closed:
+		return errorBuilderClosed
+	case b.err != nil:
+		return b.err
+	case b.data.empty():
+		return nil
+	}
+	b.pendingHandle = b.writeBlock(b.data)
+	b.pendingIndexEntry = b.err == nil
+	if b.filter != nil {
+		b.filter.startBlock(b.offset)
+	}
+	return b.err
+}
+
+// writeBlock writes the block that bb built, compressed with Options.Compression if that is worth it,
+// and resets bb.
+func (b *Builder) writeBlock(bb *blockBuilder) BlockHandle {
+	contents, c := bb.finish(), NoCompression
+	if b.opts.Compression == SnappyCompression {
+		b.compressed = snappy.Encode(b.compressed, contents)
+		// As in LevelDB, a block is only stored compressed when that saves at least 12.5%.
+		if len(b.compressed) < len(contents)-len(contents)/8 {
+			contents, c = b.compressed, SnappyCompression
+		}
+	}
+	handle := b.writeRawBlock(contents, c)
+	bb.reset()
+	return handle
+}
+
+// writeRawBlock writes contents followed by the block trailer.
+func (b *Builder) writeRawBlock(contents []byte, c Compression) BlockHandle {
+	handle := BlockHandle{Offset: b.offset, Size: uint64(len(contents))}
+	if b.err != nil {
+		return handle
+	}
+
+	crc := crc32c.New()
+	crc.Write(contents)
+	b.trailer[0] = byte(c)
+	crc.Write(b.trailer[:1])
+	binary.LittleEndian.PutUint32(b.trailer[1:], crc32c.Mask(crc.Sum32()))
+
+	if _, b.err = b.dest.Write(contents); b.err == nil {
+		_, b.err = b.dest.Write(b.trailer[:])
+	}
+	b.offset += uint64(len(contents) + blockTrailerSize)
+	return handle
+}
+
+// Finish writes the last data block, the filter block, the metaindex and index blocks and the footer.
+// The builder cannot be used afterwards.
+func (b *Builder) Finish() error {
+	if err := b.Flush(); err != nil {
+		return err
+	}
+	b.closed = true
+
+	meta := newBlockBuilder(b.opts.BlockRestartInterval)
+	if b.filter != nil {
+		handle := b.writeRawBlock(b.filter.finish(), NoCompression)
+		meta.add([]byte(filterMetaKey(b.opts.FilterPolicy)), handle.AppendTo(nil))
+	}
+	metaindex := b.writeBlock(meta)
+
+	if b.pendingIndexEntry {
+		b.addIndexEntry(b.opts.Comparer.FindShortSuccessor(b.lastKey))
+	}
+	index := b.writeBlock(b.index)
+
+	if b.err == nil {
+		_, b.err = b.dest.Write(footer{metaindex, index}.encode())
+		b.offset += footerLength
+	}
+	return b.err
+}
+
+// Abandon stops building the table. Whatever was written to dest so far should be thrown away.
+func (b *Builder) Abandon() {
+	b.closed = true
+}
+
+// NumEntries returns the number of pairs added so far.
+func (b *Builder) NumEntries() int {
+	return b.numEntries
+}
+
+// FileSize returns the size of what was written to dest so far, which is the size of the table once
+// Finish returns.
+func (b *Builder) FileSize() uint64 {
+	return b.offset
+}
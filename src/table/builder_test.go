@@ -0,0 +1,155 @@
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"crc32c"
+)
+
+// readRawBlock returns the contents of the block at handle in table, after checking its trailer.
+func readRawBlock(t *testing.T, table []byte, handle BlockHandle) []byte {
+	end := handle.Offset + handle.Size
+	contents, trailer := table[handle.Offset:end], table[end:end+blockTrailerSize]
+	if Compression(trailer[0]) != NoCompression {
+		t.Fatalf("Expected no compression but got %v", trailer[0])
+	}
+	expected := crc32c.Mask(crc32c.Checksum(table[handle.Offset : end+1]))
+	if actual := binary.LittleEndian.Uint32(trailer[1:]); actual != expected {
+		t.Fatalf("Expected checksum %#x but got %#x", expected, actual)
+	}
+	return contents
+}
+
+// blockEntries decodes the entries of a block as "key=value" strings.
+func blockEntries(t *testing.T, contents []byte) []string {
+	numRestarts := int(binary.LittleEndian.Uint32(contents[len(contents)-4:]))
+	data := contents[:len(contents)-4-4*numRestarts]
+	var entries []string
+	var key []byte
+	for len(data) > 0 {
+		shared, n1 := binary.Uvarint(data)
+		nonShared, n2 := binary.Uvarint(data[n1:])
+		valueLength, n3 := binary.Uvarint(data[n1+n2:])
+		data = data[n1+n2+n3:]
+		key = append(key[:shared], data[:nonShared]...)
+		value := data[nonShared : nonShared+valueLength]
+		data = data[nonShared+valueLength:]
+		entries = append(entries, fmt.Sprintf("%s=%s", key, value))
+	}
+	return entries
+}
+
+func TestBuilder_ShouldWriteDataIndexAndFooter(t *testing.T) {
+	var buf bytes.Buffer
+	b := NewBuilder(&buf, Options{BlockSize: 32})
+	pairs := []string{"apple", "apricot", "cherry", "date", "fig"}
+	for _, key := range pairs {
+		if err := b.Add([]byte(key), []byte("v-"+key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	table := buf.Bytes()
+	if b.FileSize() != uint64(len(table)) || b.NumEntries() != len(pairs) {
+		t.Errorf("Expected %v bytes and %v entries but got %v and %v", len(table), len(pairs), b.FileSize(), b.NumEntries())
+	}
+	f, err := decodeFooter(table[len(table)-footerLength:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := blockEntries(t, readRawBlock(t, table, f.metaindex)); len(entries) != 0 {
+		t.Errorf("Expected an empty metaindex but got %v", entries)
+	}
+
+	var indexKeys, data []string
+	for _, entry := range blockEntries(t, readRawBlock(t, table, f.index)) {
+		i := bytes.IndexByte([]byte(entry), '=')
+		indexKeys = append(indexKeys, entry[:i])
+		handle, _, err := decodeBlockHandle([]byte(entry[i+1:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, blockEntries(t, readRawBlock(t, table, handle))...)
+	}
+
+	var expectedData []string
+	for _, key := range pairs {
+		expectedData = append(expectedData, key+"=v-"+key)
+	}
+	if !reflect.DeepEqual(data, expectedData) {
+		t.Errorf("Expected %v but got %v", expectedData, data)
+	}
+	// Each block holds two pairs. The index keys separate the blocks and the last one is the short
+	// successor of the last key.
+	if expected := []string{"b", "e", "g"}; !reflect.DeepEqual(indexKeys, expected) {
+		t.Errorf("Expected index keys %v but got %v", expected, indexKeys)
+	}
+}
+
+func TestBuilder_EmptyTable(t *testing.T) {
+	var buf bytes.Buffer
+	b := NewBuilder(&buf, Options{})
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	table := buf.Bytes()
+	f, err := decodeFooter(table[len(table)-footerLength:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := blockEntries(t, readRawBlock(t, table, f.index)); len(entries) != 0 {
+		t.Errorf("Expected an empty index but got %v", entries)
+	}
+}
+
+func TestBuilder_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	b := NewBuilder(&buf, Options{})
+	b.Add([]byte("b"), nil)
+
+	if err := b.Add([]byte("a"), nil); err != errorKeyOutOfOrder {
+		t.Errorf("Expected '%v' but got '%v'", errorKeyOutOfOrder, err)
+	}
+	if err := b.Add([]byte("b"), nil); err != errorKeyOutOfOrder {
+		t.Errorf("Expected '%v' for a duplicate key but got '%v'", errorKeyOutOfOrder, err)
+	}
+	b.Abandon()
+	if err := b.Add([]byte("c"), nil); err != errorBuilderClosed {
+		t.Errorf("Expected '%v' but got '%v'", errorBuilderClosed, err)
+	}
+	if err := b.Finish(); err != errorBuilderClosed {
+		t.Errorf("Expected '%v' but got '%v'", errorBuilderClosed, err)
+	}
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestBuilder_WriteErrorsShouldBeSticky(t *testing.T) {
+	writeError := errors.New("disk full")
+	b := NewBuilder(failingWriter{writeError}, Options{BlockSize: 1})
+
+	if err := b.Add([]byte("a"), []byte("1")); err != writeError {
+		t.Errorf("Expected '%v' but got '%v'", writeError, err)
+	}
+	if err := b.Add([]byte("b"), []byte("2")); err != writeError {
+		t.Errorf("Expected '%v' but got '%v'", writeError, err)
+	}
+	if err := b.Finish(); err != writeError {
+		t.Errorf("Expected '%v' but got '%v'", writeError, err)
+	}
+}
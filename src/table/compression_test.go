@@ -0,0 +1,185 @@
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"crc32c"
+)
+
+// blockTypes returns the compression of each data block of table.
+func blockTypes(t *testing.T, table []byte) []Compression {
+	r := openTable(t, table, Options{})
+	index := r.index.newIterator(r.opts.Comparer)
+	var types []Compression
+	for index.SeekToFirst(); index.Valid(); index.Next() {
+		handle, _, err := decodeBlockHandle(index.Value())
+		if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, Compression(table[handle.Offset+handle.Size]))
+	}
+	return types
+}
+
+func buildTableOfValues(t *testing.T, opts Options, values [][]byte) []byte {
+	var buf bytes.Buffer
+	b := NewBuilder(&buf, opts)
+	for i, value := range values {
+		key := make([]byte, 4)
+		binary.BigEndian.PutUint32(key, uint32(i))
+		if err := b.Add(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBuilder_Compression(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var compressible, random [][]byte
+	for i := 0; i < 100; i++ {
+		compressible = append(compressible, bytes.Repeat([]byte{byte(i)}, 100))
+		value := make([]byte, 100)
+		r.Read(value)
+		random = append(random, value)
+	}
+
+	tests := map[string]struct {
+		compression Compression
+		values      [][]byte
+		expected    Compression
+	}{
+		"Compressible blocks":         {SnappyCompression, compressible, SnappyCompression},
+		"Blocks that do not compress": {SnappyCompression, random, NoCompression},
+		"Compression turned off":      {NoCompression, compressible, NoCompression},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			table := buildTableOfValues(t, Options{BlockSize: 1024, Compression: test.compression}, test.values)
+			for _, c := range blockTypes(t, table) {
+				if c != test.expected {
+					t.Fatalf("Expected every block to be %v but got %v", test.expected, c)
+				}
+			}
+
+			it := openTable(t, table, Options{}).NewIterator(ReadOptions{})
+			var values [][]byte
+			for it.SeekToFirst(); it.Valid(); it.Next() {
+				values = append(values, append([]byte(nil), it.Value()...))
+			}
+			if it.Err() != nil {
+				t.Fatal(it.Err())
+			}
+			if !reflect.DeepEqual(values, test.values) {
+				t.Error("Expected the values read to be the values written but were not")
+			}
+		})
+	}
+}
+
+func TestBuilder_CompressionShouldShrinkTheTable(t *testing.T) {
+	var values [][]byte
+	for i := 0; i < 1000; i++ {
+		values = append(values, []byte("the quick brown fox jumps over the lazy dog"))
+	}
+	uncompressed := buildTableOfValues(t, Options{}, values)
+	compressed := buildTableOfValues(t, Options{Compression: SnappyCompression}, values)
+
+	if len(compressed) >= len(uncompressed)/2 {
+		t.Errorf("Expected compression to halve %v bytes but got %v", len(uncompressed), len(compressed))
+	}
+}
+
+func TestReader_ShouldReportCorruptCompressedBlocks(t *testing.T) {
+	// A table of a single snappy block that claims to decode to more than it holds, with a valid
+	// checksum.
+	contents := []byte{0x40, 0x00, 'a'}
+	var buf bytes.Buffer
+	buf.Write(contents)
+	buf.WriteByte(byte(SnappyCompression))
+	var checksum [4]byte
+	binary.LittleEndian.PutUint32(checksum[:], crc32c.Mask(crc32c.Checksum(buf.Bytes())))
+	buf.Write(checksum[:])
+	buf.Write(footer{index: BlockHandle{0, uint64(len(contents))}}.encode())
+
+	_, err := Open(tableFile(t, buf.Bytes()), int64(buf.Len()), 9, Options{})
+	if e, ok := err.(CorruptionError); !ok || e.Reason != "corrupted compressed block contents" {
+		t.Errorf("Expected a corrupted compressed block but got '%v'", err)
+	}
+}
+
+// leveldbValue is the value of key i in testdata/leveldb.ldb: text that snappy compresses for the first
+// half of the keys, pseudo-random bytes that it cannot compress for the second half.
+func leveldbValue(i int) []byte {
+	if i < 50 {
+		return bytes.Repeat([]byte(fmt.Sprintf("value of key%03d ", i)), 8)
+	}
+	value := make([]byte, 64)
+	x := uint64(i)
+	for j := range value {
+		x = x*6364136223846793005 + 1442695040888963407
+		value[j] = byte(x >> 56)
+	}
+	return value
+}
+
+func TestReader_ShouldReadATableWrittenByLevelDB(t *testing.T) {
+	// Written by the TableBuilder of LevelDB with snappy compression and 256 byte blocks, see
+	// testdata/leveldb_table.cc.
+	table, err := ioutil.ReadFile("testdata/leveldb.ldb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := map[Compression]bool{}
+	for _, c := range blockTypes(t, table) {
+		types[c] = true
+	}
+	if !types[SnappyCompression] || !types[NoCompression] {
+		t.Fatalf("Expected both snappy and uncompressed blocks but got %v", types)
+	}
+
+	r := openTable(t, table, Options{})
+	it := r.NewIterator(ReadOptions{})
+	i := 0
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		if key := fmt.Sprintf("key%03d", i); string(it.Key()) != key || !bytes.Equal(it.Value(), leveldbValue(i)) {
+			t.Fatalf("Expected %v with its value but got %q, %q", key, it.Key(), it.Value())
+		}
+		i++
+	}
+	if it.Err() != nil || i != 100 {
+		t.Errorf("Expected 100 entries but got %v, '%v'", i, it.Err())
+	}
+	it.Release()
+
+	for _, i := range []int{0, 49, 50, 99} {
+		key := fmt.Sprintf("key%03d", i)
+		if k, v, err := r.Get(ReadOptions{}, []byte(key)); err != nil || string(k) != key || !bytes.Equal(v, leveldbValue(i)) {
+			t.Errorf("Expected to get %v with its value but got %q, %q, '%v'", key, k, v, err)
+		}
+	}
+}
+
+func TestCompression_String(t *testing.T) {
+	tests := map[Compression]string{
+		NoCompression:     "none",
+		SnappyCompression: "snappy",
+		Compression(7):    "Invalid compression 7",
+	}
+
+	for c, expected := range tests {
+		if actual := c.String(); actual != expected {
+			t.Errorf("Expected %v but got %v", expected, actual)
+		}
+	}
+}
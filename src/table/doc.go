@@ -0,0 +1,50 @@
+// Package table reads and writes sorted tables, the immutable files of sorted key-value pairs that
+// full memtables are written to, in LevelDB's .ldb format.
+//
+// Table format
+//
+// A table is a sequence of blocks followed by a fixed size footer:
+//
+//     table := data block* filter block? metaindex block index block footer
+//     block := contents trailer
+//     trailer :=
+//       type: uint8           // the compression of contents
+//       checksum: uint32      // masked crc32c of contents and type ; little-endian
+//
+// Data blocks hold the key-value pairs in key order. The index block has an entry for each data block,
+// whose key is at least the last key of the data block and less than the first key of the next one, and
+// whose value is the BlockHandle of the data block. The metaindex block maps the names of meta blocks,
+// such as filters, to their handles. The footer holds the handles of the metaindex and index blocks:
+//
+//     footer :=
+//       metaindex handle: BlockHandle
+//       index handle: BlockHandle
+//       padding: uint8[40 - size of the handles]
+//       magic: fixed64        // 0xdb4775248b80fb57 ; little-endian
+//
+// Block format
+//
+// The keys in a block are prefix compressed: each entry only stores the part of its key that differs
+// from the key before it. Every Options.BlockRestartInterval entries the full key is stored instead, at a restart
+// point, and the offsets of the restart points end the block, so that a key can be looked up by a binary
+// search over them:
+//
+//     contents := entry* restarts: fixed32[num restarts] num restarts: fixed32
+//     entry :=
+//       shared: varint32      // the length of the prefix shared with the previous key
+//       non shared: varint32
+//       value length: varint32
+//       key delta: uint8[non shared]
+//       value: uint8[value length]
+//
+// Filter block
+//
+// A table written with a filter policy has a filter block, which the metaindex block maps to from
+// "filter." followed by the name of the policy. It holds a filter for every 2KB of data block offsets,
+// made from the keys of the data blocks that start in that range:
+//
+//     filter block := filter* offsets: fixed32[num filters] array offset: fixed32 base lg: uint8
+//
+// The ith offset is where the ith filter starts, the array offset is where the offsets start, and base
+// lg is 11, the log2 of 2KB.
+package table
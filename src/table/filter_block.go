@@ -0,0 +1,133 @@
+package table
+
+import (
+	"encoding/binary"
+
+	"filter"
+)
+
+// filterBaseLg is the log2 of the range of data block offsets that a filter covers. A filter is made for
+// every 2KB of the table, holding the keys of the data blocks that start in that range.
+const filterBaseLg = 11
+
+// filterBlockBuilder builds the filter block of a table. It must be told where each data block starts,
+// with startBlock, before the keys of the block are added.
+//
+//	filter block := filter* offsets: fixed32[num filters] array offset: fixed32 base lg: uint8
+//
+// where the ith offset is where the ith filter starts, and the array offset where the offsets start.
+type filterBlockBuilder struct {
+	policy filter.FilterPolicy
+
+	// keys are the keys for the next filter, all in one slice, and starts where each of them starts.
+	keys   []byte
+	starts []int
+	result []byte
+	// offsets are where each filter made so far starts in result.
+	offsets []uint32
+
+	args [][]byte
+}
+
+func newFilterBlockBuilder(policy filter.FilterPolicy) *filterBlockBuilder {
+	return &filterBlockBuilder{policy: policy}
+}
+
+// startBlock makes the filters for the ranges before the data block at blockOffset.
+func (b *filterBlockBuilder) startBlock(blockOffset uint64) {
+	for index := blockOffset >> filterBaseLg; index > uint64(len(b.offsets)); {
+		b.generateFilter()
+	}
+}
+
+func (b *filterBlockBuilder) addKey(key []byte) {
+	b.starts = append(b.starts, len(b.keys))
+	b.keys = append(b.keys, key...)
+}
+
+// finish returns the contents of the filter block.
+func (b *filterBlockBuilder) finish() []byte {
+	if len(b.starts) > 0 {
+		b.generateFilter()
+	}
+
+	var fixed [4]byte
+	arrayOffset := uint32(len(b.result))
+	for _, offset := range b.offsets {
+		binary.LittleEndian.PutUint32(fixed[:], offset)
+		b.result = append(b.result, fixed[:]...)
+	}
+	binary.LittleEndian.PutUint32(fixed[:], arrayOffset)
+	b.result = append(b.result, fixed[:]...)
+	return append(b.result, filterBaseLg)
+}
+
+// generateFilter makes the filter of the keys added since the last one, which is empty when there are
+// none.
+func (b *filterBlockBuilder) generateFilter() {
+	b.offsets = append(b.offsets, uint32(len(b.result)))
+	if len(b.starts) == 0 {
+		return
+	}
+
+	b.args = b.args[:0]
+	for i, start := range b.starts {
+		end := len(b.keys)
+		if i+1 < len(b.starts) {
+			end = b.starts[i+1]
+		}
+		b.args = append(b.args, b.keys[start:end])
+	}
+	b.result = b.policy.AppendFilter(b.result, b.args)
+
+	b.keys = b.keys[:0]
+	b.starts = b.starts[:0]
+}
+
+// filterBlockReader tests keys against the filters of a filter block.
+type filterBlockReader struct {
+	policy filter.FilterPolicy
+	data   []byte
+	// offsets are the offsets of the filters in data, followed by the array offset.
+	offsets []byte
+	num     int
+	baseLg  uint
+}
+
+// newFilterBlockReader returns a reader of the filter block contents. A malformed block gives a reader
+// that matches every key.
+func newFilterBlockReader(policy filter.FilterPolicy, contents []byte) *filterBlockReader {
+	r := &filterBlockReader{policy: policy}
+	n := len(contents)
+	if n < 5 {
+		return r
+	}
+	r.baseLg = uint(contents[n-1])
+	arrayOffset := binary.LittleEndian.Uint32(contents[n-5:])
+	if uint64(arrayOffset) > uint64(n-5) {
+		return r
+	}
+	r.data = contents[:arrayOffset]
+	r.offsets = contents[arrayOffset : n-1]
+	r.num = (n - 5 - int(arrayOffset)) / 4
+	return r
+}
+
+// keyMayMatch returns false when key is certainly not in the data block at blockOffset.
+func (r *filterBlockReader) keyMayMatch(blockOffset uint64, key []byte) bool {
+	index := blockOffset >> r.baseLg
+	if index >= uint64(r.num) {
+		// Errors count as a match.
+		return true
+	}
+	start := binary.LittleEndian.Uint32(r.offsets[4*index:])
+	limit := binary.LittleEndian.Uint32(r.offsets[4*index+4:])
+	switch {
+	case start == limit:
+		// An empty filter covers no blocks.
+		return false
+	case start < limit && uint64(limit) <= uint64(len(r.data)):
+		return r.policy.KeyMayMatch(key, r.data[start:limit])
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package table
+
+import (
+	"bytes"
+	"testing"
+)
+
+// keysPolicy is a filter policy whose filters are the keys themselves, each followed by a 0 byte.
+type keysPolicy struct{}
+
+func (keysPolicy) Name() string {
+	return "test.KeysPolicy"
+}
+
+func (keysPolicy) AppendFilter(dst []byte, keys [][]byte) []byte {
+	for _, key := range keys {
+		dst = append(append(dst, key...), 0)
+	}
+	return dst
+}
+
+func (keysPolicy) KeyMayMatch(key, filter []byte) bool {
+	for _, k := range bytes.Split(filter[:len(filter)-1], []byte{0}) {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFilterBlock_Empty(t *testing.T) {
+	contents := newFilterBlockBuilder(keysPolicy{}).finish()
+	if expected := []byte{0, 0, 0, 0, filterBaseLg}; !bytes.Equal(contents, expected) {
+		t.Errorf("Expected %v but got %v", expected, contents)
+	}
+
+	r := newFilterBlockReader(keysPolicy{}, contents)
+	for _, offset := range []uint64{0, 100000} {
+		if !r.keyMayMatch(offset, []byte("foo")) {
+			t.Errorf("Expected a table without filters to match at %v but it did not", offset)
+		}
+	}
+}
+
+func TestFilterBlock_SingleChunk(t *testing.T) {
+	b := newFilterBlockBuilder(keysPolicy{})
+	b.startBlock(100)
+	b.addKey([]byte("foo"))
+	b.addKey([]byte("bar"))
+	b.addKey([]byte("box"))
+	b.startBlock(200)
+	b.addKey([]byte("box"))
+	b.startBlock(300)
+	b.addKey([]byte("hello"))
+	r := newFilterBlockReader(keysPolicy{}, b.finish())
+
+	for key, expected := range map[string]bool{"foo": true, "bar": true, "box": true, "hello": true, "missing": false, "other": false} {
+		if actual := r.keyMayMatch(100, []byte(key)); actual != expected {
+			t.Errorf("Expected keyMayMatch(%v) to be %v but got %v", key, expected, actual)
+		}
+	}
+}
+
+func TestFilterBlock_MultiChunk(t *testing.T) {
+	b := newFilterBlockBuilder(keysPolicy{})
+	// The first filter covers the blocks starting in the first 2KB.
+	b.startBlock(0)
+	b.addKey([]byte("foo"))
+	b.startBlock(2000)
+	b.addKey([]byte("bar"))
+	// The second filter.
+	b.startBlock(3100)
+	b.addKey([]byte("box"))
+	// The third filter is empty, and the fourth follows.
+	b.startBlock(9000)
+	b.addKey([]byte("box"))
+	b.addKey([]byte("hello"))
+	r := newFilterBlockReader(keysPolicy{}, b.finish())
+
+	tests := map[string]struct {
+		offset   uint64
+		expected map[string]bool
+	}{
+		"First filter":  {0, map[string]bool{"foo": true, "bar": true, "box": false, "hello": false}},
+		"Second filter": {3100, map[string]bool{"foo": false, "bar": false, "box": true, "hello": false}},
+		"Empty filter":  {4100, map[string]bool{"foo": false, "bar": false, "box": false, "hello": false}},
+		"Last filter":   {9000, map[string]bool{"foo": false, "bar": false, "box": true, "hello": true}},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			for key, expected := range test.expected {
+				if actual := r.keyMayMatch(test.offset, []byte(key)); actual != expected {
+					t.Errorf("Expected keyMayMatch(%v) to be %v but got %v", key, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterBlockReader_ShouldMatchEverythingForMalformedBlocks(t *testing.T) {
+	tests := map[string][]byte{
+		"Too short":        {0, 0, 0},
+		"Bad array offset": {0, 0, 0, 0, 100, 0, 0, 0, filterBaseLg},
+	}
+
+	for testName, contents := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if !newFilterBlockReader(keysPolicy{}, contents).keyMayMatch(0, []byte("foo")) {
+				t.Error("Expected a match but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package table
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"filter"
+)
+
+const (
+	// blockTrailerSize is the size of the compression type and checksum that follow every block.
+	blockTrailerSize = 5
+
+	// maxBlockHandleLength is the longest encoding of a BlockHandle, two varint64s.
+	maxBlockHandleLength = 2 * binary.MaxVarintLen64
+
+	// footerLength is the size of the footer, which has room for two handles of any length.
+	footerLength = 2*maxBlockHandleLength + 8
+
+	// tableMagicNumber ends every table. It was picked by running
+	// echo http://code.google.com/p/leveldb/ | sha1sum and taking the leading 64 bits.
+	tableMagicNumber = 0xdb4775248b80fb57
+)
+
+// Compression is the algorithm blocks are compressed with. Its values are the type bytes of the block
+// trailers.
+type Compression uint8
+
+const (
+	NoCompression     Compression = 0x0
+	SnappyCompression Compression = 0x1
+)
+
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case SnappyCompression:
+		return "snappy"
+	default:
+		return fmt.Sprintf("Invalid compression %d", int(c))
+	}
+}
+
+var errorBadBlockHandle = errors.New("table: bad block handle")
+var errorBadMagicNumber = errors.New("table: not an sstable (bad magic number)")
+
+// filterMetaKey is the key of the filter block of policy in the metaindex block.
+func filterMetaKey(policy filter.FilterPolicy) string {
+	return "filter." + policy.Name()
+}
+
+// TableFileName returns the name of the table numbered number in dir.
+func TableFileName(dir string, number uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%06d.ldb", number))
+}
+
+// BlockHandle is where a block is in a table. Size does not include the block trailer.
+type BlockHandle struct {
+	Offset uint64
+	Size   uint64
+}
+
+// AppendTo appends the encoding of h to dst.
+func (h BlockHandle) AppendTo(dst []byte) []byte {
+	var buf [maxBlockHandleLength]byte
+	n := binary.PutUvarint(buf[:], h.Offset)
+	n += binary.PutUvarint(buf[n:], h.Size)
+	return append(dst, buf[:n]...)
+}
+
+// decodeBlockHandle returns the handle at the start of p and the length of its encoding.
+func decodeBlockHandle(p []byte) (BlockHandle, int, error) {
+	offset, n := binary.Uvarint(p)
+	if n <= 0 {
+		return BlockHandle{}, 0, errorBadBlockHandle
+	}
+	size, m := binary.Uvarint(p[n:])
+	if m <= 0 {
+		return BlockHandle{}, 0, errorBadBlockHandle
+	}
+	return BlockHandle{offset, size}, n + m, nil
+}
+
+// footer holds the handles that the last footerLength bytes of a table point to.
+type footer struct {
+	metaindex BlockHandle
+	index     BlockHandle
+}
+
+func (f footer) encode() []byte {
+	buf := make([]byte, footerLength)
+	f.index.AppendTo(f.metaindex.AppendTo(buf[:0]))
+	binary.LittleEndian.PutUint64(buf[footerLength-8:], tableMagicNumber)
+	return buf
+}
+
+func decodeFooter(p []byte) (footer, error) {
+	if len(p) != footerLength || binary.LittleEndian.Uint64(p[footerLength-8:]) != tableMagicNumber {
+		return footer{}, errorBadMagicNumber
+	}
+	metaindex, n, err := decodeBlockHandle(p)
+	if err != nil {
+		return footer{}, err
+	}
+	index, _, err := decodeBlockHandle(p[n:])
+	if err != nil {
+		return footer{}, err
+	}
+	return footer{metaindex, index}, nil
+}
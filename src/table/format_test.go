@@ -0,0 +1,66 @@
+package table
+
+import (
+	"testing"
+)
+
+func TestBlockHandle_RoundTrip(t *testing.T) {
+	tests := map[string]BlockHandle{
+		"Zero":    {0, 0},
+		"Small":   {10, 100},
+		"Large":   {1 << 40, 1 << 30},
+		"Largest": {1<<64 - 1, 1<<64 - 1},
+	}
+
+	for testName, handle := range tests {
+		t.Run(testName, func(t *testing.T) {
+			encoded := handle.AppendTo(nil)
+			if len(encoded) > maxBlockHandleLength {
+				t.Errorf("Expected at most %v bytes but got %v", maxBlockHandleLength, len(encoded))
+			}
+			decoded, n, err := decodeBlockHandle(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded != handle || n != len(encoded) {
+				t.Errorf("Expected %v in %v bytes but got %v in %v", handle, len(encoded), decoded, n)
+			}
+		})
+	}
+}
+
+func TestDecodeBlockHandle_ShouldRejectTruncatedHandles(t *testing.T) {
+	encoded := BlockHandle{1 << 20, 1 << 20}.AppendTo(nil)
+	for n := 0; n < len(encoded); n++ {
+		if _, _, err := decodeBlockHandle(encoded[:n]); err != errorBadBlockHandle {
+			t.Errorf("Expected '%v' for %v bytes but got '%v'", errorBadBlockHandle, n, err)
+		}
+	}
+}
+
+func TestFooter_RoundTrip(t *testing.T) {
+	f := footer{metaindex: BlockHandle{1000, 20}, index: BlockHandle{1025, 300}}
+
+	encoded := f.encode()
+	if len(encoded) != footerLength {
+		t.Errorf("Expected %v bytes but got %v", footerLength, len(encoded))
+	}
+	decoded, err := decodeFooter(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != f {
+		t.Errorf("Expected %v but got %v", f, decoded)
+	}
+
+	encoded[footerLength-1] ^= 0x1
+	if _, err := decodeFooter(encoded); err != errorBadMagicNumber {
+		t.Errorf("Expected '%v' but got '%v'", errorBadMagicNumber, err)
+	}
+}
+
+func TestTableFileName(t *testing.T) {
+	if actual := TableFileName("db", 7); actual != "db/000007.ldb" {
+		t.Errorf("Expected db/000007.ldb but got %v", actual)
+	}
+}
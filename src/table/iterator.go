@@ -0,0 +1,60 @@
+package table
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned by Reader.Get when the table has no key at or after the key looked for.
+var ErrNotFound = errors.New("table: not found")
+
+// CorruptionError is a damaged part of a table.
+type CorruptionError struct {
+	FileNumber uint64
+	// Offset is where the damaged block starts in the table.
+	Offset uint64
+	Reason string
+}
+
+func (e CorruptionError) Error() string {
+	return fmt.Sprintf("table %06d: block at offset %d: %s", e.FileNumber, e.Offset, e.Reason)
+}
+
+// Iterator iterates over the key-value pairs of a table, or a block, in key order. It is not positioned
+// at a pair until one of its seek methods is called. The key and value are only valid until it moves.
+type Iterator interface {
+	// Valid returns whether the iterator is positioned at a pair. It is false after an error.
+	Valid() bool
+	// SeekToFirst moves to the first pair.
+	SeekToFirst()
+	// SeekToLast moves to the last pair.
+	SeekToLast()
+	// Seek moves to the first pair whose key is at least target.
+	Seek(target []byte)
+	// Next moves to the next pair. The iterator must be valid.
+	Next()
+	// Prev moves to the previous pair. The iterator must be valid.
+	Prev()
+	Key() []byte
+	Value() []byte
+	// Err returns the error that made the iterator invalid, if any.
+	Err() error
+	// Release gives back the cached blocks the iterator holds. The iterator cannot be used afterwards.
+	Release()
+}
+
+// errorIterator is an iterator with no pairs, which failed with err.
+type errorIterator struct {
+	err error
+}
+
+func (it errorIterator) Valid() bool        { return false }
+func (it errorIterator) SeekToFirst()       {}
+func (it errorIterator) SeekToLast()        {}
+func (it errorIterator) Seek(target []byte) {}
+func (it errorIterator) Next()              {}
+func (it errorIterator) Prev()              {}
+func (it errorIterator) Key() []byte        { return nil }
+func (it errorIterator) Value() []byte      { return nil }
+func (it errorIterator) Err() error         { return it.err }
+func (it errorIterator) Release()           {}
@@ -0,0 +1,69 @@
+package table
+
+import (
+	"cache"
+	"comparer"
+	"filter"
+)
+
+const (
+	// DefaultBlockSize is the size a data block is filled to unless told otherwise.
+	DefaultBlockSize = 4096
+	// DefaultBlockRestartInterval is the number of keys between restart points unless told otherwise.
+	DefaultBlockRestartInterval = 16
+)
+
+// Options control how a table is built and read.
+type Options struct {
+	// Comparer orders the keys of the table. It must be the same for writing and reading a table.
+	// comparer.BytewiseComparator is used when it is nil.
+	Comparer comparer.Comparator
+
+	// BlockSize is the approximate size of the uncompressed contents of a data block.
+	// DefaultBlockSize is used when it is zero.
+	BlockSize int
+
+	// BlockRestartInterval is the number of keys between restart points in a data block, which trades
+	// the space saved by prefix compression against the cost of a lookup. DefaultBlockRestartInterval
+	// is used when it is zero.
+	BlockRestartInterval int
+
+	// Compression is the algorithm blocks are compressed with. A block that compression shrinks by less
+	// than 12.5% is stored uncompressed. The reader decompresses blocks whatever their compression.
+	Compression Compression
+
+	// FilterPolicy makes the builder write a filter block, and the reader use it to skip the data
+	// blocks that cannot hold a key. A table's filter is only used when it was written by a policy with
+	// the same name.
+	FilterPolicy filter.FilterPolicy
+
+	// BlockCache keeps the data and index blocks the reader reads in memory, charging them by their
+	// uncompressed size. It can be shared by the readers of many tables. Blocks are read from the table
+	// every time when it is nil.
+	BlockCache cache.Cache
+}
+
+// ReadOptions control a single read of a table. DefaultReadOptions returns the options of a plain read.
+type ReadOptions struct {
+	// FillCache adds the blocks read to Options.BlockCache. Blocks are looked up in the cache either
+	// way; a large scan should leave it unset so that it does not evict the blocks others need.
+	FillCache bool
+}
+
+// DefaultReadOptions returns the options LevelDB reads with by default, which fill the block cache.
+func DefaultReadOptions() ReadOptions {
+	return ReadOptions{FillCache: true}
+}
+
+func (o Options) withDefaults() Options {
+	if o.Comparer == nil {
+		o.Comparer = comparer.BytewiseComparator
+	}
+	if o.BlockSize == 0 {
+		o.BlockSize = DefaultBlockSize
+	}
+	if o.BlockRestartInterval == 0 {
+		o.BlockRestartInterval = DefaultBlockRestartInterval
+	}
+	return o
+}
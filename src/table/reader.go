@@ -0,0 +1,239 @@
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"comparer"
+	"crc32c"
+	"snappy"
+)
+
+// Reader reads a table through an io.ReaderAt, like an env.File. Every block it reads is checked against
+// its checksum. It is safe to use from multiple goroutines, as long as src is.
+type Reader struct {
+	src        io.ReaderAt
+	size       uint64
+	fileNumber uint64
+	opts       Options
+	// cacheID prefixes the keys of the blocks of the table in Options.BlockCache.
+	cacheID uint64
+
+	indexHandle BlockHandle
+	// index is kept in memory when there is no block cache to keep it in.
+	index  *block
+	filter *filterBlockReader
+}
+
+// Open opens the table of size bytes in src, which is numbered fileNumber in corruption errors. It reads
+// the footer and the index block, which goes into Options.BlockCache when there is one.
+func Open(src io.ReaderAt, size int64, fileNumber uint64, opts Options) (*Reader, error) {
+	r := &Reader{src: src, size: uint64(size), fileNumber: fileNumber, opts: opts.withDefaults()}
+	if r.opts.BlockCache != nil {
+		r.cacheID = r.opts.BlockCache.NewID()
+	}
+	if size < footerLength {
+		return nil, CorruptionError{fileNumber, 0, "file is too short to be an sstable"}
+	}
+
+	buf := make([]byte, footerLength)
+	if _, err := src.ReadAt(buf, size-footerLength); err != nil {
+		return nil, err
+	}
+	f, err := decodeFooter(buf)
+	if err != nil {
+		return nil, CorruptionError{fileNumber, uint64(size - footerLength), err.Error()}
+	}
+
+	r.indexHandle = f.index
+	index, err := r.readBlock(f.index)
+	if err != nil {
+		return nil, err
+	}
+	if r.opts.BlockCache != nil {
+		h := r.opts.BlockCache.Insert(r.cacheKey(f.index), index, int64(len(index.data)), nil)
+		r.opts.BlockCache.Release(h)
+	} else {
+		r.index = index
+	}
+	if r.opts.FilterPolicy != nil {
+		r.readFilter(f.metaindex)
+	}
+	return r, nil
+}
+
+// readFilter reads the filter block of Options.FilterPolicy, if the table has one. A table that cannot
+// be filtered can still be read, so errors are ignored.
+func (r *Reader) readFilter(metaindexHandle BlockHandle) {
+	metaindex, err := r.readBlock(metaindexHandle)
+	if err != nil {
+		return
+	}
+	key := []byte(filterMetaKey(r.opts.FilterPolicy))
+	it := metaindex.newIterator(comparer.BytewiseComparator)
+	it.Seek(key)
+	if !it.Valid() || !bytes.Equal(it.Key(), key) {
+		return
+	}
+	handle, _, err := decodeBlockHandle(it.Value())
+	if err != nil {
+		return
+	}
+	if contents, err := r.readBlockContents(handle); err == nil {
+		r.filter = newFilterBlockReader(r.opts.FilterPolicy, contents)
+	}
+}
+
+// readBlock reads and parses the block at handle.
+func (r *Reader) readBlock(handle BlockHandle) (*block, error) {
+	contents, err := r.readBlockContents(handle)
+	if err != nil {
+		return nil, err
+	}
+	return newBlock(contents, r.fileNumber, handle.Offset)
+}
+
+// readBlockContents reads the contents of the block at handle and checks its trailer. A handle that
+// reaches past the end of the table is rejected before anything is allocated for it.
+func (r *Reader) readBlockContents(handle BlockHandle) ([]byte, error) {
+	if handle.Size > r.size-blockTrailerSize || handle.Offset > r.size-blockTrailerSize-handle.Size {
+		return nil, CorruptionError{r.fileNumber, handle.Offset, "bad block handle"}
+	}
+	buf := make([]byte, handle.Size+blockTrailerSize)
+	n, err := r.src.ReadAt(buf, int64(handle.Offset))
+	if n < len(buf) {
+		if err == nil || err == io.EOF {
+			return nil, CorruptionError{r.fileNumber, handle.Offset, "truncated block read"}
+		}
+		return nil, err
+	}
+
+	contents, trailer := buf[:handle.Size], buf[handle.Size:]
+	stored := binary.LittleEndian.Uint32(trailer[1:])
+	if computed := crc32c.Mask(crc32c.Checksum(buf[:handle.Size+1])); stored != computed {
+		return nil, CorruptionError{r.fileNumber, handle.Offset, "block checksum mismatch"}
+	}
+
+	switch Compression(trailer[0]) {
+	case NoCompression:
+		return contents, nil
+	case SnappyCompression:
+		decoded, err := snappy.Decode(nil, contents)
+		if err != nil {
+			return nil, CorruptionError{r.fileNumber, handle.Offset, "corrupted compressed block contents"}
+		}
+		return decoded, nil
+	}
+	return nil, CorruptionError{r.fileNumber, handle.Offset, "bad block type"}
+}
+
+// cacheKey returns the key of the block at handle in Options.BlockCache: the cache ID of the table
+// followed by the offset of the block, both as fixed64.
+func (r *Reader) cacheKey(handle BlockHandle) []byte {
+	var key [16]byte
+	binary.LittleEndian.PutUint64(key[:], r.cacheID)
+	binary.LittleEndian.PutUint64(key[8:], handle.Offset)
+	return key[:]
+}
+
+// iterateBlock returns an iterator over the block at handle, which comes from Options.BlockCache when
+// the block is there. The iterator holds on to the cached block until it is released.
+func (r *Reader) iterateBlock(handle BlockHandle, opts ReadOptions) (*blockIterator, error) {
+	c := r.opts.BlockCache
+	if c == nil {
+		b, err := r.readBlock(handle)
+		if err != nil {
+			return nil, err
+		}
+		return b.newIterator(r.opts.Comparer), nil
+	}
+
+	key := r.cacheKey(handle)
+	h := c.Lookup(key)
+	if h == nil {
+		b, err := r.readBlock(handle)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.FillCache {
+			return b.newIterator(r.opts.Comparer), nil
+		}
+		h = c.Insert(key, b, int64(len(b.data)), nil)
+	}
+	it := h.Value().(*block).newIterator(r.opts.Comparer)
+	it.blockCache, it.handle = c, h
+	return it, nil
+}
+
+// indexIterator returns an iterator over the index block.
+func (r *Reader) indexIterator(opts ReadOptions) (*blockIterator, error) {
+	if r.index != nil {
+		return r.index.newIterator(r.opts.Comparer), nil
+	}
+	return r.iterateBlock(r.indexHandle, opts)
+}
+
+// openBlock returns an iterator over the data block with the encoded handle, as found in the index.
+func (r *Reader) openBlock(encodedHandle []byte, opts ReadOptions) (*blockIterator, error) {
+	handle, _, err := decodeBlockHandle(encodedHandle)
+	if err != nil {
+		return nil, CorruptionError{r.fileNumber, r.indexHandle.Offset, "bad block handle in index"}
+	}
+	return r.iterateBlock(handle, opts)
+}
+
+// NewIterator returns an iterator over the pairs of the table, which must be released once done with.
+func (r *Reader) NewIterator(opts ReadOptions) Iterator {
+	index, err := r.indexIterator(opts)
+	if err != nil {
+		return errorIterator{err}
+	}
+	return newTwoLevelIterator(index, func(handle []byte) (*blockIterator, error) {
+		return r.openBlock(handle, opts)
+	})
+}
+
+// Get looks key up with a single data block read. It returns the first pair of that block whose key is at
+// least key, which is where a pair with key would be, or ErrNotFound when there is none. The caller
+// decides whether the key returned is a match, as it may be another version of the same user key for
+// instance. When the filter of the table rules key out, Get returns ErrNotFound without reading the
+// block.
+func (r *Reader) Get(opts ReadOptions, key []byte) ([]byte, []byte, error) {
+	index, err := r.indexIterator(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Blocks are left to the garbage collector once out of the cache, so the pair returned stays valid
+	// after the iterators are released.
+	defer index.Release()
+	index.Seek(key)
+	if !index.Valid() {
+		if index.Err() != nil {
+			return nil, nil, index.Err()
+		}
+		return nil, nil, ErrNotFound
+	}
+
+	// The index key of a block is at least its last key and less than the first key of the next block,
+	// so the block is the only one that can hold key.
+	if r.filter != nil {
+		handle, _, err := decodeBlockHandle(index.Value())
+		if err == nil && !r.filter.keyMayMatch(handle.Offset, key) {
+			return nil, nil, ErrNotFound
+		}
+	}
+	data, err := r.openBlock(index.Value(), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer data.Release()
+	data.Seek(key)
+	if !data.Valid() {
+		if data.Err() != nil {
+			return nil, nil, data.Err()
+		}
+		return nil, nil, ErrNotFound
+	}
+	return data.Key(), data.Value(), nil
+}
@@ -0,0 +1,423 @@
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"cache"
+	"comparer"
+	"crc32c"
+	"env"
+	"filter"
+)
+
+// buildTable builds a table of keys, each with the value "v-" followed by the key.
+func buildTable(t *testing.T, opts Options, keys []string) []byte {
+	var buf bytes.Buffer
+	b := NewBuilder(&buf, opts)
+	for _, key := range keys {
+		if err := b.Add([]byte(key), []byte("v-"+key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// tableFile stores table as table 5 of a MemFS and opens it for reading.
+func tableFile(t *testing.T, table []byte) env.File {
+	fs := env.NewMemFS()
+	f, err := fs.Create(TableFileName(".", 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(table); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := fs.Open(TableFileName(".", 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func openTable(t *testing.T, table []byte, opts Options) *Reader {
+	r, err := Open(tableFile(t, table), int64(len(table)), 5, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestReader_Iterator(t *testing.T) {
+	tests := map[string]struct {
+		opts Options
+		n    int
+	}{
+		"Empty table":           {Options{}, 0},
+		"Single block":          {Options{}, 10},
+		"Many blocks":           {Options{BlockSize: 256}, 500},
+		"One pair per block":    {Options{BlockSize: 1}, 50},
+		"No prefix compression": {Options{BlockSize: 256, BlockRestartInterval: 1}, 200},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			keys := testKeys(test.n)
+			r := openTable(t, buildTable(t, test.opts, keys), test.opts)
+			it := r.NewIterator(ReadOptions{})
+
+			it.SeekToFirst()
+			if actual := collectForward(it); !reflect.DeepEqual(actual, keys) {
+				t.Errorf("Expected %v keys forward but got %v", len(keys), len(actual))
+			}
+			it.SeekToLast()
+			if actual := collectBackward(it); !reflect.DeepEqual(actual, keys) {
+				t.Errorf("Expected %v keys backward but got %v", len(keys), len(actual))
+			}
+			if it.Err() != nil {
+				t.Error(it.Err())
+			}
+
+			for i := 0; i <= 2*test.n; i++ {
+				it.Seek([]byte(fmt.Sprintf("key%04d", i)))
+				if (i+1)/2 == test.n {
+					if it.Valid() {
+						t.Errorf("Expected Seek past the last key to be invalid but got %s", it.Key())
+					}
+					continue
+				}
+				expected := keys[(i+1)/2]
+				if !it.Valid() || string(it.Key()) != expected || string(it.Value()) != "v-"+expected {
+					t.Fatalf("Expected Seek(%v) to find %v", i, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestReader_IteratorShouldChangeDirection(t *testing.T) {
+	keys := testKeys(100)
+	r := openTable(t, buildTable(t, Options{BlockSize: 64}, keys), Options{})
+	it := r.NewIterator(ReadOptions{})
+
+	it.Seek([]byte("key0100"))
+	it.Prev()
+	it.Prev()
+	it.Next()
+	if !it.Valid() || string(it.Key()) != "key0098" {
+		t.Errorf("Expected key0098 but got %s", it.Key())
+	}
+	it.SeekToFirst()
+	it.Prev()
+	if it.Valid() {
+		t.Errorf("Expected Prev before the first key to be invalid but got %s", it.Key())
+	}
+}
+
+func TestReader_Get(t *testing.T) {
+	keys := testKeys(100)
+	r := openTable(t, buildTable(t, Options{BlockSize: 64}, keys), Options{})
+
+	for i, key := range keys {
+		k, v, err := r.Get(ReadOptions{}, []byte(key))
+		if err != nil || string(k) != key || string(v) != "v-"+key {
+			t.Fatalf("Expected %v but got %s, %s, '%v'", key, k, v, err)
+		}
+		k, _, err = r.Get(ReadOptions{}, []byte(fmt.Sprintf("key%04d", 2*i+1)))
+		if err != ErrNotFound && (err != nil || string(k) <= key) {
+			t.Fatalf("Expected a key after %v or not found but got %s, '%v'", key, k, err)
+		}
+	}
+	if _, _, err := r.Get(ReadOptions{}, []byte("zzz")); err != ErrNotFound {
+		t.Errorf("Expected '%v' but got '%v'", ErrNotFound, err)
+	}
+}
+
+func TestReader_ShouldReportCorruption(t *testing.T) {
+	keys := testKeys(100)
+	table := buildTable(t, Options{BlockSize: 256}, keys)
+	f, err := decodeFooter(table[len(table)-footerLength:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Data block", func(t *testing.T) {
+		damaged := append([]byte(nil), table...)
+		damaged[300] ^= 0x1
+		r := openTable(t, damaged, Options{})
+
+		it := r.NewIterator(ReadOptions{})
+		it.SeekToFirst()
+		collectForward(it)
+		e, ok := it.Err().(CorruptionError)
+		if !ok || e.FileNumber != 5 || e.Offset == 0 || e.Offset > 300 {
+			t.Errorf("Expected a corruption error of table 5 in the second block but got '%v'", it.Err())
+		}
+		if _, _, err := r.Get(ReadOptions{}, []byte(keys[len(keys)-1])); err != nil {
+			t.Errorf("Expected the last block to be readable but got '%v'", err)
+		}
+	})
+
+	tests := map[string]func([]byte) []byte{
+		"Index block": func(table []byte) []byte {
+			table[f.index.Offset] ^= 0x1
+			return table
+		},
+		"Magic number": func(table []byte) []byte {
+			table[len(table)-1] ^= 0x1
+			return table
+		},
+		"Truncated file": func(table []byte) []byte {
+			return table[:footerLength-1]
+		},
+		"Truncated index block": func(table []byte) []byte {
+			return table[f.index.Offset+1:]
+		},
+	}
+	for testName, damage := range tests {
+		t.Run(testName, func(t *testing.T) {
+			damaged := damage(append([]byte(nil), table...))
+			_, err := Open(tableFile(t, damaged), int64(len(damaged)), 5, Options{})
+			if e, ok := err.(CorruptionError); !ok || e.FileNumber != 5 {
+				t.Errorf("Expected a corruption error of table 5 but got '%v'", err)
+			}
+		})
+	}
+}
+
+// withIndex replaces the index block of table, which ends in f, with one that maps key to handle.
+func withIndex(table []byte, f footer, key string, handle BlockHandle) []byte {
+	index := newBlockBuilder(1)
+	index.add([]byte(key), handle.AppendTo(nil))
+	contents := index.finish()
+
+	damaged := append([]byte(nil), table[:f.index.Offset]...)
+	f.index = BlockHandle{Offset: uint64(len(damaged)), Size: uint64(len(contents))}
+	damaged = append(damaged, contents...)
+	var trailer [blockTrailerSize]byte
+	trailer[0] = byte(NoCompression)
+	binary.LittleEndian.PutUint32(trailer[1:], crc32c.Mask(crc32c.Checksum(append(contents, trailer[0]))))
+	damaged = append(damaged, trailer[:]...)
+	return append(damaged, f.encode()...)
+}
+
+func TestReader_ShouldRejectBadBlockHandles(t *testing.T) {
+	keys := testKeys(100)
+	table := buildTable(t, Options{BlockSize: 256}, keys)
+	f, err := decodeFooter(table[len(table)-footerLength:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := uint64(len(table))
+	handles := map[string]BlockHandle{
+		"Past the end":         {Offset: 0, Size: size},
+		"Huge size":            {Offset: 0, Size: 1 << 62},
+		"Overflowing size":     {Offset: 16, Size: math.MaxUint64 - 8},
+		"Overflowing offset":   {Offset: math.MaxUint64 - 8, Size: 16},
+		"Offset past the end":  {Offset: size, Size: 0},
+		"Trailer past the end": {Offset: size - blockTrailerSize - 1, Size: 2},
+	}
+
+	for testName, handle := range handles {
+		t.Run("Footer/"+testName, func(t *testing.T) {
+			damaged := append(append([]byte(nil), table[:len(table)-footerLength]...), footer{f.metaindex, handle}.encode()...)
+			_, err := Open(tableFile(t, damaged), int64(len(damaged)), 5, Options{})
+			if e, ok := err.(CorruptionError); !ok || e.FileNumber != 5 || e.Offset != handle.Offset {
+				t.Errorf("Expected a corruption error of table 5 at %v but got '%v'", handle.Offset, err)
+			}
+		})
+
+		t.Run("Index/"+testName, func(t *testing.T) {
+			r := openTable(t, withIndex(table, f, keys[len(keys)-1], handle), Options{})
+			it := r.NewIterator(ReadOptions{})
+			it.SeekToFirst()
+			if e, ok := it.Err().(CorruptionError); it.Valid() || !ok || e.FileNumber != 5 || e.Offset != handle.Offset {
+				t.Errorf("Expected a corruption error of table 5 at %v but got '%v'", handle.Offset, it.Err())
+			}
+			it.Release()
+		})
+	}
+}
+
+// countingReaderAt counts the reads of a table.
+type countingReaderAt struct {
+	io.ReaderAt
+	reads int
+}
+
+func (r *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.reads++
+	return r.ReaderAt.ReadAt(p, off)
+}
+
+func TestReader_GetShouldUseTheFilter(t *testing.T) {
+	keys := testKeys(100)
+	tests := map[string]struct {
+		writer, reader Options
+		expectedReads  int
+	}{
+		"Filtered":                {Options{BlockSize: 64, FilterPolicy: keysPolicy{}}, Options{FilterPolicy: keysPolicy{}}, 0},
+		"Bloom filter":            {Options{BlockSize: 64, FilterPolicy: filter.NewBloomFilterPolicy(10)}, Options{FilterPolicy: filter.NewBloomFilterPolicy(10)}, 0},
+		"Reader without a policy": {Options{BlockSize: 64, FilterPolicy: keysPolicy{}}, Options{}, 50},
+		"Table without a filter":  {Options{BlockSize: 64}, Options{FilterPolicy: keysPolicy{}}, 50},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			table := buildTable(t, test.writer, keys)
+			src := &countingReaderAt{ReaderAt: tableFile(t, table)}
+			r, err := Open(src, int64(len(table)), 5, test.reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, key := range keys {
+				if k, _, err := r.Get(ReadOptions{}, []byte(key)); err != nil || string(k) != key {
+					t.Fatalf("Expected to find %v but got %s, '%v'", key, k, err)
+				}
+			}
+
+			src.reads = 0
+			for i := 0; i < 50; i++ {
+				// Each of the missing keys sorts before a key of the table.
+				missing := []byte(fmt.Sprintf("key%04d", 2*i+1))
+				if k, _, err := r.Get(ReadOptions{}, missing); err != nil && err != ErrNotFound || string(k) == string(missing) {
+					t.Fatalf("Expected not to find %s but got %s, '%v'", missing, k, err)
+				}
+			}
+			// A bloom filter may let a few missing keys through.
+			if src.reads < test.expectedReads || src.reads > test.expectedReads+2 {
+				t.Errorf("Expected about %v reads but got %v", test.expectedReads, src.reads)
+			}
+		})
+	}
+}
+
+func TestReader_BlockCache(t *testing.T) {
+	keys := testKeys(100)
+	table := buildTable(t, Options{BlockSize: 64}, keys)
+	index := openTable(t, table, Options{}).index.newIterator(comparer.BytewiseComparator)
+	index.SeekToFirst()
+	numBlocks := len(collectForward(index))
+
+	tests := map[string]struct {
+		opts          ReadOptions
+		expectedReads int
+		expectedHits  int64
+	}{
+		// Open caches the index block, and the first round of lookups the data blocks.
+		"Default": {DefaultReadOptions(), numBlocks, 3*100 + 3*100 - int64(numBlocks)},
+		// Only the index block is ever cached.
+		"Don't fill cache": {ReadOptions{}, 3 * 100, 3 * 100},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			c := cache.NewLRUCache(1 << 20)
+			src := &countingReaderAt{ReaderAt: tableFile(t, table)}
+			r, err := Open(src, int64(len(table)), 5, Options{BlockCache: c})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			src.reads = 0
+			for round := 0; round < 3; round++ {
+				for _, key := range keys {
+					if k, _, err := r.Get(test.opts, []byte(key)); err != nil || string(k) != key {
+						t.Fatalf("Expected to find %v but got %s, '%v'", key, k, err)
+					}
+				}
+			}
+			if src.reads != test.expectedReads {
+				t.Errorf("Expected %v reads but got %v", test.expectedReads, src.reads)
+			}
+			if hits := c.Stats().Hits; hits != test.expectedHits {
+				t.Errorf("Expected %v cache hits but got %v", test.expectedHits, hits)
+			}
+		})
+	}
+}
+
+func TestReader_DefaultReadOptionsShouldFillTheCache(t *testing.T) {
+	keys := testKeys(100)
+	table := buildTable(t, Options{BlockSize: 64}, keys)
+	c := cache.NewLRUCache(1 << 20)
+	r := openTable(t, table, Options{BlockCache: c})
+
+	charge := c.TotalCharge()
+	if _, _, err := r.Get(DefaultReadOptions(), []byte(keys[50])); err != nil {
+		t.Fatal(err)
+	}
+	if c.TotalCharge() <= charge {
+		t.Errorf("Expected a read with the default options to cache its data block but the charge stayed at %v", charge)
+	}
+}
+
+func TestReader_ScanShouldNotEvictTheHotSetWithoutFillCache(t *testing.T) {
+	keys := testKeys(1000)
+	table := buildTable(t, Options{BlockSize: 64}, keys)
+	// The cache holds a few dozen data blocks, far from all of them.
+	c := cache.NewLRUCache(16 * 1024)
+	r, err := Open(tableFile(t, table), int64(len(table)), 5, Options{BlockCache: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hot := []byte(keys[0])
+	if _, _, err := r.Get(DefaultReadOptions(), hot); err != nil {
+		t.Fatal(err)
+	}
+	charge := c.TotalCharge()
+
+	it := r.NewIterator(ReadOptions{})
+	it.SeekToFirst()
+	if actual := collectForward(it); !reflect.DeepEqual(actual, keys) {
+		t.Errorf("Expected %v keys but got %v", len(keys), len(actual))
+	}
+	it.Release()
+	if c.Stats().Evictions != 0 || c.TotalCharge() != charge {
+		t.Errorf("Expected the scan to leave the cache alone but got %+v and a charge of %v", c.Stats(), c.TotalCharge())
+	}
+
+	it = r.NewIterator(DefaultReadOptions())
+	it.SeekToFirst()
+	collectForward(it)
+	it.Release()
+	if c.Stats().Evictions == 0 {
+		t.Errorf("Expected a filling scan to evict blocks but got %+v", c.Stats())
+	}
+}
+
+func TestReader_IteratorShouldPinItsBlocks(t *testing.T) {
+	keys := testKeys(100)
+	table := buildTable(t, Options{BlockSize: 64}, keys)
+	c := cache.NewLRUCache(1 << 20)
+	r := openTable(t, table, Options{BlockCache: c})
+
+	it := r.NewIterator(DefaultReadOptions())
+	it.Seek([]byte(keys[50]))
+	c.Prune()
+	if c.TotalCharge() == 0 {
+		t.Errorf("Expected the index and current data blocks to stay cached while in use")
+	}
+	if !it.Valid() || string(it.Key()) != keys[50] {
+		t.Errorf("Expected %v but got %s", keys[50], it.Key())
+	}
+
+	it.Release()
+	c.Prune()
+	if c.TotalCharge() != 0 {
+		t.Errorf("Expected nothing cached after pruning released blocks but got a charge of %v", c.TotalCharge())
+	}
+}
@@ -0,0 +1,46 @@
+// Writes leveldb.ldb with the TableBuilder of LevelDB, for TestReader_ShouldReadATableWrittenByLevelDB.
+// Build it against LevelDB with snappy support and run it in this directory:
+//
+//   g++ -std=c++17 leveldb_table.cc -lleveldb -lsnappy -o leveldb_table && ./leveldb_table
+#include <cstdint>
+#include <cstdio>
+#include <string>
+
+#include "leveldb/env.h"
+#include "leveldb/options.h"
+#include "leveldb/table_builder.h"
+
+// The value of key i: text that snappy compresses for the first half of the keys, pseudo-random
+// bytes that it cannot compress for the second half.
+static std::string Value(int i) {
+  char key[16];
+  snprintf(key, sizeof(key), "key%03d", i);
+  std::string v;
+  if (i < 50) {
+    for (int j = 0; j < 8; j++) v += std::string("value of ") + key + " ";
+    return v;
+  }
+  uint64_t x = i;
+  for (int j = 0; j < 64; j++) {
+    x = x * 6364136223846793005ULL + 1442695040888963407ULL;
+    v.push_back(static_cast<char>(x >> 56));
+  }
+  return v;
+}
+
+int main() {
+  leveldb::Options opts;
+  opts.block_size = 256;
+  opts.compression = leveldb::kSnappyCompression;
+  leveldb::WritableFile* f;
+  if (!leveldb::Env::Default()->NewWritableFile("leveldb.ldb", &f).ok()) return 1;
+  leveldb::TableBuilder b(opts, f);
+  for (int i = 0; i < 100; i++) {
+    char key[16];
+    snprintf(key, sizeof(key), "key%03d", i);
+    b.Add(key, Value(i));
+  }
+  if (!b.Finish().ok() || !f->Close().ok()) return 1;
+  delete f;
+  return 0;
+}
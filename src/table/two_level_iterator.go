@@ -0,0 +1,145 @@
+package table
+
+import "bytes"
+
+// twoLevelIterator iterates over the pairs of a table by iterating over its index block, and over the
+// data block that the current index entry points to.
+type twoLevelIterator struct {
+	index *blockIterator
+	// openBlock returns an iterator over the data block with the encoded handle.
+	openBlock func(handle []byte) (*blockIterator, error)
+
+	data *blockIterator
+	// dataHandle is the handle of the data block that data iterates over.
+	dataHandle []byte
+	err        error
+}
+
+func newTwoLevelIterator(index *blockIterator, openBlock func(handle []byte) (*blockIterator, error)) *twoLevelIterator {
+	return &twoLevelIterator{index: index, openBlock: openBlock}
+}
+
+func (it *twoLevelIterator) Valid() bool {
+	return it.err == nil && it.data != nil && it.data.Valid()
+}
+
+func (it *twoLevelIterator) Key() []byte {
+	return it.data.Key()
+}
+
+func (it *twoLevelIterator) Value() []byte {
+	return it.data.Value()
+}
+
+func (it *twoLevelIterator) Err() error {
+	switch {
+	case it.err != nil:
+		return it.err
+	case it.index.Err() != nil:
+		return it.index.Err()
+	case it.data != nil:
+		return it.data.Err()
+	}
+	return nil
+}
+
+func (it *twoLevelIterator) Seek(target []byte) {
+	it.index.Seek(target)
+	if it.initDataBlock() {
+		it.data.Seek(target)
+	}
+	it.skipEmptyDataBlocksForward()
+}
+
+func (it *twoLevelIterator) SeekToFirst() {
+	it.index.SeekToFirst()
+	if it.initDataBlock() {
+		it.data.SeekToFirst()
+	}
+	it.skipEmptyDataBlocksForward()
+}
+
+func (it *twoLevelIterator) SeekToLast() {
+	it.index.SeekToLast()
+	if it.initDataBlock() {
+		it.data.SeekToLast()
+	}
+	it.skipEmptyDataBlocksBackward()
+}
+
+func (it *twoLevelIterator) Next() {
+	it.data.Next()
+	it.skipEmptyDataBlocksForward()
+}
+
+func (it *twoLevelIterator) Prev() {
+	it.data.Prev()
+	it.skipEmptyDataBlocksBackward()
+}
+
+// stopped returns whether the iterator cannot move on, because of an error or because the current
+// data block has more pairs.
+func (it *twoLevelIterator) stopped() bool {
+	return it.err != nil || it.data != nil && (it.data.Valid() || it.data.Err() != nil)
+}
+
+func (it *twoLevelIterator) skipEmptyDataBlocksForward() {
+	for !it.stopped() {
+		if !it.index.Valid() {
+			it.setData(nil)
+			return
+		}
+		it.index.Next()
+		if it.initDataBlock() {
+			it.data.SeekToFirst()
+		}
+	}
+}
+
+func (it *twoLevelIterator) skipEmptyDataBlocksBackward() {
+	for !it.stopped() {
+		if !it.index.Valid() {
+			it.setData(nil)
+			return
+		}
+		it.index.Prev()
+		if it.initDataBlock() {
+			it.data.SeekToLast()
+		}
+	}
+}
+
+// initDataBlock opens the data block of the current index entry, unless it is open already. It returns
+// false when there is no such block.
+func (it *twoLevelIterator) initDataBlock() bool {
+	if !it.index.Valid() {
+		it.setData(nil)
+		return false
+	}
+	handle := it.index.Value()
+	if it.data != nil && bytes.Equal(handle, it.dataHandle) {
+		return true
+	}
+	data, err := it.openBlock(handle)
+	if err != nil {
+		it.err = err
+		it.setData(nil)
+		return false
+	}
+	it.setData(data)
+	it.dataHandle = append(it.dataHandle[:0], handle...)
+	return true
+}
+
+// setData replaces the data block iterator, releasing the block of the previous one.
+func (it *twoLevelIterator) setData(data *blockIterator) {
+	if it.data != nil {
+		it.data.Release()
+	}
+	it.data = data
+}
+
+func (it *twoLevelIterator) Release() {
+	it.setData(nil)
+	it.index.Release()
+}